@@ -1,37 +1,71 @@
 package client
 
 import (
+	"context"
 	"log"
-	"math/rand"
-	"os"
 	"time"
 
 	"github.com/streadway/amqp"
 )
 
+const DefaultTimeout = 30 * time.Second
+
+// DirectReplyTo is the RabbitMQ pseudo queue used to receive replies without
+// declaring a queue per request.
+const DirectReplyTo = "amq.rabbitmq.reply-to"
+
 type Authorization struct {
 	Username    string
 	Authorities string
 }
 
-func SendMessage(exchange string, routingKey string, body string, verbose bool) (err error) {
-	return SendMessageWithHeaders(exchange, routingKey, body, nil, verbose)
+func SendMessage(ctx context.Context, exchange string, routingKey string, body string, verbose bool) (err error) {
+	return SendMessageWithHeaders(ctx, exchange, routingKey, body, nil, verbose)
+}
+
+func SendMessageWithHeaders(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) (err error) {
+	return DefaultBroker().SendMessageWithHeaders(ctx, exchange, routingKey, body, headers, verbose)
+}
+
+// SendAndReceive waits for the reply until ctx is done or, when ctx has no
+// deadline, for DefaultTimeout.
+func SendAndReceive(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) (res string, err error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	return DefaultBroker().SendAndReceive(ctx, exchange, routingKey, body, headers, verbose)
+}
+
+// SendMessageWithHeaders publishes a persistent message, retrying on new
+// channels or connections when the broker fails with a transient error.
+// Cancelling ctx stops the retries and the wait for the confirmation.
+func (b *Broker) SendMessageWithHeaders(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) (err error) {
+	started := time.Now()
+	err = b.retry(ctx, verbose, func() error {
+		return b.sendMessage(ctx, exchange, routingKey, body, headers, verbose)
+	})
+	b.record(Record{
+		Type:       RecordPublish,
+		Exchange:   exchange,
+		RoutingKey: routingKey,
+		Headers:    headers,
+		Body:       body,
+	}, err, started)
+	return err
 }
 
-func SendMessageWithHeaders(exchange string, routingKey string, body string, headers amqp.Table, verbose bool) (err error) {
-	amqpUri := "amqp://" + os.Getenv("APP_AMQP_URI")
-	conn, err := amqp.Dial(amqpUri)
+func (b *Broker) sendMessage(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) (err error) {
+	trace, err := b.traceContext()
 	if err != nil {
-		log.Fatalf("%s: %s", "Error opening connection", err)
 		return err
 	}
-	defer conn.Close()
-	ch, err := conn.Channel()
+	ch, err := b.Channel()
 	if err != nil {
-		log.Fatalf("%s: %s", "Error opening channel", err)
 		return err
 	}
-	defer ch.Close()
+	defer func() { b.Release(ch, err != nil) }()
 	if verbose {
 		log.Printf("Sending message: %s", body)
 	}
@@ -40,97 +74,182 @@ func SendMessageWithHeaders(exchange string, routingKey string, body string, hea
 		DeliveryMode: amqp.Persistent,
 		Timestamp:    time.Now(),
 		ContentType:  "text/plain",
-		Headers:      headers,
+		Headers:      trace.inject(headers),
 		Body:         []byte(body),
 	}
+	if err = b.config.Message.apply(&msg); err != nil {
+		return err
+	}
+	b.config.Signing.sign(&msg)
 
-	err = ch.Publish(
-		exchange,
-		routingKey,
-		false, // mandatory
-		false, // inmediate
-		msg)
-	if err != nil {
-		log.Fatalf("%s: %s", "Error opening connection", err)
-	} else if verbose {
-		log.Printf("Sent message")
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+	err = b.publish(ctx, ch, exchange, routingKey, msg)
+	if err == nil && verbose {
+		log.Printf("Message accepted and routed by the broker")
 	}
 	return err
 }
 
-func SendAndReceive(exchange string, routingKey string, body string, headers amqp.Table, verbose bool) (res string, err error) {
-	amqpUri := "amqp://" + os.Getenv("APP_AMQP_URI")
-	conn, err := amqp.Dial(amqpUri)
+// SendAndReceive publishes a request and waits for the reply with the same
+// correlation id until ctx is done. ErrReplyTimeout is returned when the
+// deadline is exceeded. Transient broker failures are retried, timeouts are
+// not.
+func (b *Broker) SendAndReceive(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) (res string, err error) {
+	started := time.Now()
+	corrId := NewCorrelationId()
+	if verbose {
+		log.Printf("Using correlation id: %s", corrId)
+	}
+	err = b.retry(ctx, verbose, func() (err error) {
+		var replies []string
+		replies, err = b.sendAndCollect(ctx, exchange, routingKey, body, headers, corrId, CollectOptions{}, verbose)
+		if len(replies) > 0 {
+			res = replies[0]
+		}
+		return err
+	})
+	b.record(Record{
+		Type:          RecordRequest,
+		Exchange:      exchange,
+		RoutingKey:    routingKey,
+		Headers:       headers,
+		Body:          body,
+		CorrelationId: corrId,
+		Reply:         res,
+	}, err, started)
+	return
+}
+
+func (b *Broker) sendAndCollect(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, corrId string, collect CollectOptions, verbose bool) (replies []string, err error) {
+	trace, err := b.traceContext()
 	if err != nil {
-		log.Fatalf("%s: %s", "Error opening connection", err)
-		return "", err
+		return nil, err
 	}
-	defer conn.Close()
-	ch, err := conn.Channel()
+	ch, err := b.Channel()
 	if err != nil {
-		log.Fatalf("%s: %s", "Error opening channel", err)
-		return "", err
+		return nil, err
 	}
-	defer ch.Close()
+	defer func() { b.Release(ch, err != nil) }()
 	if verbose {
 		log.Printf("Sending message: %s", body)
 	}
 
-	q, err := ch.QueueDeclare(
-		"",    // name
-		false, // durable
-		false, // delete when usused
-		true,  // exclusive
-		false, // noWait
-		nil,   // arguments
-	)
+	replyTo := DirectReplyTo
+	if !b.config.DirectReplyTo {
+		q, err := ch.QueueDeclare(
+			"",    // name
+			false, // durable
+			true,  // delete when usused
+			true,  // exclusive
+			false, // noWait
+			nil,   // arguments
+		)
+		if err != nil {
+			return nil, channelError("declare reply queue", err)
+		}
+		replyTo = q.Name
+	}
 
 	msgs, err := ch.Consume(
-		q.Name, // queue
-		"",     // consumer
-		true,   // auto-ack
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		nil,    // args
+		replyTo, // queue
+		corrId,  // consumer
+		true,    // auto-ack
+		false,   // exclusive
+		false,   // no-local
+		false,   // no-wait
+		nil,     // args
 	)
+	if err != nil {
+		return nil, channelError("consume replies", err)
+	}
+	defer ch.Cancel(corrId, false)
 
-	corrId := randomString(32)
-
-	if verbose {
-		log.Printf("Using correlation id: %s", corrId)
+	msg := amqp.Publishing{
+		ContentType:   "text/plain",
+		CorrelationId: corrId,
+		ReplyTo:       replyTo,
+		Headers:       trace.inject(headers),
+		Body:          []byte(body),
+	}
+	if err = b.config.Message.apply(&msg); err != nil {
+		return nil, err
 	}
+	b.config.Signing.sign(&msg)
 
-	err = ch.Publish(
-		exchange,   // exchange
-		routingKey, // routing key
-		false,      // mandatory
-		false,      // immediate
-		amqp.Publishing{
-			ContentType:   "text/plain",
-			CorrelationId: corrId,
-			ReplyTo:       q.Name,
-			Headers:       headers,
-			Body:          []byte(body),
-		})
-
-	for d := range msgs {
-		if corrId == d.CorrelationId {
-			res = string(d.Body)
-			break
-		}
+	err = b.publish(ctx, ch, exchange, routingKey, msg)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return collectReplies(ctx, msgs, corrId, collect)
 }
 
-func randomString(l int) string {
-	bytes := make([]byte, l)
-	for i := 0; i < l; i++ {
-		bytes[i] = byte(randInt(65, 90))
+// SendReply answers a request through the default exchange to its ReplyTo
+// queue with the correlation id of the request.
+func (b *Broker) SendReply(ctx context.Context, replyTo string, correlationId string, body string, headers amqp.Table) (err error) {
+	ch, err := b.Channel()
+	if err != nil {
+		return err
+	}
+	defer func() { b.Release(ch, err != nil) }()
+
+	msg := amqp.Publishing{
+		Timestamp:     time.Now(),
+		ContentType:   "text/plain",
+		CorrelationId: correlationId,
+		Headers:       headers,
+		Body:          []byte(body),
+	}
+	if err = b.config.Message.apply(&msg); err != nil {
+		return err
 	}
-	return string(bytes)
+	b.config.Signing.sign(&msg)
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+	return b.publish(ctx, ch, "", replyTo, msg)
 }
 
-func randInt(min int, max int) int {
-	return min + rand.Intn(max-min)
+func SendReply(ctx context.Context, replyTo string, correlationId string, body string, headers amqp.Table) error {
+	return DefaultBroker().SendReply(ctx, replyTo, correlationId, body, headers)
+}
+
+// publish sends a mandatory message and waits for the broker confirmation,
+// reporting messages the broker could not route or did not accept.
+func (b *Broker) publish(ctx context.Context, ch *amqp.Channel, exchange string, routingKey string, msg amqp.Publishing) error {
+	state, err := b.confirmMode(ch)
+	if err != nil {
+		return channelError("enable confirms", err)
+	}
+	exchange = b.config.ExchangeName(exchange)
+	err = ch.Publish(
+		exchange,
+		routingKey,
+		true,  // mandatory
+		false, // inmediate
+		msg)
+	if err != nil {
+		return channelError("publish", err)
+	}
+	select {
+	case confirm, ok := <-state.confirms:
+		if !ok {
+			if closeErr := <-state.closed; closeErr != nil {
+				return channelError("publish", closeErr)
+			}
+			return channelError("publish", amqp.ErrClosed)
+		}
+		// The broker always sends basic.return before the ack of the same message
+		select {
+		case ret := <-state.returns:
+			return &UnroutableError{Exchange: exchange, RoutingKey: routingKey, Reason: ret.ReplyText}
+		default:
+		}
+		if !confirm.Ack {
+			return &Error{Kind: ErrNacked, Op: "publish"}
+		}
+		return nil
+	case <-ctx.Done():
+		return contextError("wait confirmation", ctx.Err())
+	}
 }
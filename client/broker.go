@@ -0,0 +1,267 @@
+package client
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+const defaultChannelPoolSize = 16
+
+// Broker owns a single AMQP connection and a pool of channels so that every
+// message sent by a process reuses the same TCP connection.
+type Broker struct {
+	config   Config
+	mutex    sync.Mutex
+	conn     *amqp.Connection
+	channels chan *amqp.Channel
+	state    map[*amqp.Channel]*channelState
+	recorder *Recorder
+	trace    *traceContext
+}
+
+// Config holds the connection settings of a Broker.
+type Config struct {
+	Uri      string
+	TLS      TLSOptions
+	PoolSize int
+	Retry    RetryPolicy
+	// Receive replies through amq.rabbitmq.reply-to instead of an exclusive
+	// queue declared for each request
+	DirectReplyTo bool
+	// File where every message sent and reply received is appended
+	Record  string
+	Message MessageOptions
+	Trace   TraceOptions
+	Signing SigningOptions
+	// Exchanges renames the exchanges messages are sent to and consumed from,
+	// so the same commands work against environments with other names
+	Exchanges map[string]string
+}
+
+type channelState struct {
+	closed   chan *amqp.Error
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+}
+
+var defaultConfig *Config
+var defaultBroker *Broker
+var defaultBrokerMutex sync.Mutex
+
+func NewBroker(config Config) *Broker {
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultChannelPoolSize
+	}
+	return &Broker{
+		config:   config,
+		channels: make(chan *amqp.Channel, config.PoolSize),
+		state:    make(map[*amqp.Channel]*channelState),
+	}
+}
+
+// ConfigFromEnv reads the broker settings from APP_AMQP_URI and the
+// APP_AMQP_* TLS, retry, message and signing variables and the APP_TRACE_*
+// ones.
+func ConfigFromEnv() Config {
+	return Config{
+		Uri:           AmqpUri(os.Getenv("APP_AMQP_URI")),
+		TLS:           TLSOptionsFromEnv(),
+		PoolSize:      defaultChannelPoolSize,
+		Retry:         RetryPolicyFromEnv(),
+		DirectReplyTo: os.Getenv("APP_AMQP_DIRECT_REPLY_TO") == "true",
+		Message:       MessageOptionsFromEnv(),
+		Trace:         TraceOptionsFromEnv(),
+		Signing:       SigningOptionsFromEnv(),
+	}
+}
+
+// DefaultConfig returns the settings used to build the default broker. They
+// are loaded from the environment and may be overridden by command flags
+// before the first message is sent.
+func DefaultConfig() *Config {
+	defaultBrokerMutex.Lock()
+	defer defaultBrokerMutex.Unlock()
+	if defaultConfig == nil {
+		config := ConfigFromEnv()
+		defaultConfig = &config
+	}
+	return defaultConfig
+}
+
+// ExchangeName returns the exchange used for name after the renames of
+// Exchanges.
+func (c *Config) ExchangeName(name string) string {
+	if renamed, ok := c.Exchanges[name]; ok {
+		return renamed
+	}
+	return name
+}
+
+// AmqpUri accepts both full amqp:// or amqps:// URIs and the legacy
+// user:pass@host:port/vhost format, which is assumed to be plain amqp.
+func AmqpUri(value string) string {
+	if strings.Contains(value, "://") {
+		return value
+	}
+	return "amqp://" + value
+}
+
+// DefaultBroker returns the process wide broker built from DefaultConfig. The
+// connection is opened lazily on first use and released by Close.
+func DefaultBroker() *Broker {
+	config := DefaultConfig()
+	defaultBrokerMutex.Lock()
+	defer defaultBrokerMutex.Unlock()
+	if defaultBroker == nil {
+		defaultBroker = NewBroker(*config)
+	}
+	return defaultBroker
+}
+
+// Close releases the default broker connection if it has been opened.
+func Close() error {
+	defaultBrokerMutex.Lock()
+	defer defaultBrokerMutex.Unlock()
+	if defaultBroker == nil {
+		return nil
+	}
+	err := defaultBroker.Close()
+	defaultBroker = nil
+	return err
+}
+
+func (b *Broker) Open() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.open()
+}
+
+func (b *Broker) open() error {
+	if b.conn != nil && !b.conn.IsClosed() {
+		return nil
+	}
+	b.drainChannels()
+	tlsConfig, err := b.config.TLS.TLSConfig()
+	if err != nil {
+		return &Error{Kind: ErrConfiguration, Op: "configure tls", Err: err}
+	}
+	// The TLS configuration is only used by amqps URIs
+	conn, err := amqp.DialTLS(b.config.Uri, tlsConfig)
+	if err != nil {
+		return dialError(b.config.Uri, err)
+	}
+	b.conn = conn
+	return nil
+}
+
+func (b *Broker) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.drainChannels()
+	if b.recorder != nil {
+		b.recorder.Close()
+		b.recorder = nil
+	}
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	if err == amqp.ErrClosed {
+		return nil
+	}
+	return err
+}
+
+// Channel takes a channel from the pool, opening the connection and a new
+// channel when required. Channels must be given back with Release.
+func (b *Broker) Channel() (*amqp.Channel, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err := b.open(); err != nil {
+		return nil, err
+	}
+	for {
+		select {
+		case ch := <-b.channels:
+			if !b.isClosed(ch) {
+				return ch, nil
+			}
+			delete(b.state, ch)
+		default:
+			ch, err := b.conn.Channel()
+			if err != nil {
+				return nil, channelError("open channel", err)
+			}
+			b.state[ch] = &channelState{closed: ch.NotifyClose(make(chan *amqp.Error, 1))}
+			return ch, nil
+		}
+	}
+}
+
+// Release returns a channel to the pool. Channels that failed are closed
+// instead, since the broker may have already shut them down.
+func (b *Broker) Release(ch *amqp.Channel, failed bool) {
+	if ch == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !failed && !b.isClosed(ch) {
+		select {
+		case b.channels <- ch:
+			return
+		default:
+		}
+	}
+	delete(b.state, ch)
+	ch.Close()
+}
+
+// confirmMode puts the channel in publisher confirm mode the first time it is
+// used for publishing and returns the listeners for acks and returns.
+func (b *Broker) confirmMode(ch *amqp.Channel) (*channelState, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	state := b.state[ch]
+	if state.confirms != nil {
+		return state, nil
+	}
+	if err := ch.Confirm(false); err != nil {
+		return nil, err
+	}
+	state.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	state.returns = ch.NotifyReturn(make(chan amqp.Return, 1))
+	return state, nil
+}
+
+// isClosed reports whether the server closed the channel since it was opened,
+// which happens asynchronously for example when publishing to a missing
+// exchange.
+func (b *Broker) isClosed(ch *amqp.Channel) bool {
+	state, ok := b.state[ch]
+	if !ok {
+		return true
+	}
+	select {
+	case <-state.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *Broker) drainChannels() {
+	for {
+		select {
+		case ch := <-b.channels:
+			delete(b.state, ch)
+			ch.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// CollectOptions defines when a request with several replies is complete:
+// after Count replies, after a reply carrying EndHeader with a true value, or
+// when no reply arrives for Quiet. Without options only one reply is read.
+type CollectOptions struct {
+	Count     int           `json:"count,omitempty"`
+	EndHeader string        `json:"endHeader,omitempty"`
+	Quiet     time.Duration `json:"quiet,omitempty"`
+}
+
+// Enabled reports whether more than a single reply has to be collected.
+func (o CollectOptions) Enabled() bool {
+	return o.Count > 1 || o.EndHeader != "" || o.Quiet > 0
+}
+
+func (o CollectOptions) isLast(d *amqp.Delivery, received int) bool {
+	if o.Count > 0 && received >= o.Count {
+		return true
+	}
+	if o.EndHeader != "" {
+		value, ok := d.Headers[o.EndHeader]
+		return ok && isTrue(value)
+	}
+	return !o.Enabled()
+}
+
+func isTrue(value interface{}) bool {
+	switch strings.ToLower(fmt.Sprint(value)) {
+	case "true", "1", "yes":
+		return true
+	}
+	return false
+}
+
+// SendAndCollect publishes a request and gathers every reply with its
+// correlation id, in arrival order, until collect is satisfied. When ctx
+// expires the replies received so far are returned with ErrReplyTimeout.
+func (b *Broker) SendAndCollect(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, collect CollectOptions, verbose bool) (replies []string, err error) {
+	started := time.Now()
+	corrId := NewCorrelationId()
+	if verbose {
+		log.Printf("Using correlation id: %s", corrId)
+	}
+	err = b.retry(ctx, verbose, func() (err error) {
+		replies, err = b.sendAndCollect(ctx, exchange, routingKey, body, headers, corrId, collect, verbose)
+		return err
+	})
+	b.record(Record{
+		Type:          RecordRequest,
+		Exchange:      exchange,
+		RoutingKey:    routingKey,
+		Headers:       headers,
+		Body:          body,
+		CorrelationId: corrId,
+		Reply:         strings.Join(replies, "\n"),
+		Collect:       &collect,
+	}, err, started)
+	return
+}
+
+func SendAndCollect(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, collect CollectOptions, verbose bool) ([]string, error) {
+	return DefaultBroker().SendAndCollect(ctx, exchange, routingKey, body, headers, collect, verbose)
+}
+
+func collectReplies(ctx context.Context, msgs <-chan amqp.Delivery, corrId string, collect CollectOptions) ([]string, error) {
+	var replies []string
+	var quiet <-chan time.Time
+	for {
+		select {
+		case d, ok := <-msgs:
+			if !ok {
+				return replies, channelError("consume replies", amqp.ErrClosed)
+			}
+			if corrId != d.CorrelationId {
+				continue
+			}
+			replies = append(replies, string(d.Body))
+			if collect.isLast(&d, len(replies)) {
+				return replies, nil
+			}
+			if collect.Quiet > 0 {
+				quiet = time.After(collect.Quiet)
+			}
+		case <-quiet:
+			return replies, nil
+		case <-ctx.Done():
+			return replies, contextError("wait reply", ctx.Err())
+		}
+	}
+}
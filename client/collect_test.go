@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+func TestCollectReplies(t *testing.T) {
+	end := amqp.Table{"App-Last": "true"}
+	tests := []struct {
+		name       string
+		collect    CollectOptions
+		deliveries []amqp.Delivery
+		close      bool
+		replies    string
+		err        error
+	}{
+		{"single reply", CollectOptions{}, []amqp.Delivery{{CorrelationId: "1", Body: []byte("a")}, {CorrelationId: "1", Body: []byte("b")}}, false, "a", nil},
+		{"other correlation ids", CollectOptions{}, []amqp.Delivery{{CorrelationId: "2", Body: []byte("x")}, {CorrelationId: "1", Body: []byte("a")}}, false, "a", nil},
+		{"count", CollectOptions{Count: 2}, []amqp.Delivery{{CorrelationId: "1", Body: []byte("a")}, {CorrelationId: "1", Body: []byte("b")}, {CorrelationId: "1", Body: []byte("c")}}, false, "a,b", nil},
+		{"end header", CollectOptions{EndHeader: "App-Last"}, []amqp.Delivery{{CorrelationId: "1", Body: []byte("a")}, {CorrelationId: "1", Body: []byte("b"), Headers: end}, {CorrelationId: "1", Body: []byte("c")}}, false, "a,b", nil},
+		{"end header false", CollectOptions{EndHeader: "App-Last"}, []amqp.Delivery{{CorrelationId: "1", Body: []byte("a"), Headers: amqp.Table{"App-Last": false}}}, false, "a", ErrReplyTimeout},
+		{"count before end header", CollectOptions{Count: 1, EndHeader: "App-Last"}, []amqp.Delivery{{CorrelationId: "1", Body: []byte("a")}, {CorrelationId: "1", Body: []byte("b")}}, false, "a", nil},
+		{"quiet", CollectOptions{Quiet: 20 * time.Millisecond}, []amqp.Delivery{{CorrelationId: "1", Body: []byte("a")}, {CorrelationId: "1", Body: []byte("b")}}, false, "a,b", nil},
+		{"timeout keeps partial replies", CollectOptions{Count: 3}, []amqp.Delivery{{CorrelationId: "1", Body: []byte("a")}}, false, "a", ErrReplyTimeout},
+		{"closed channel", CollectOptions{Count: 3}, []amqp.Delivery{{CorrelationId: "1", Body: []byte("a")}}, true, "a", ErrChannel},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msgs := make(chan amqp.Delivery, len(test.deliveries))
+			for _, d := range test.deliveries {
+				msgs <- d
+			}
+			if test.close {
+				close(msgs)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			replies, err := collectReplies(ctx, msgs, "1", test.collect)
+			if joined := strings.Join(replies, ","); joined != test.replies {
+				t.Errorf("replies %q, want %q", joined, test.replies)
+			}
+			if (test.err == nil && err != nil) || (test.err != nil && !errors.Is(err, test.err)) {
+				t.Errorf("error %v, want %v", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Death is an entry of the x-death header added by RabbitMQ each time a
+// message is dead-lettered.
+type Death struct {
+	Queue       string
+	Reason      string
+	Exchange    string
+	RoutingKeys []string
+	Count       int64
+	Time        time.Time
+}
+
+// Deaths parses the x-death history of a message, most recent first.
+func Deaths(headers amqp.Table) []Death {
+	entries, _ := headers["x-death"].([]interface{})
+	deaths := make([]Death, 0, len(entries))
+	for _, entry := range entries {
+		table, ok := entry.(amqp.Table)
+		if !ok {
+			continue
+		}
+		death := Death{}
+		death.Queue, _ = table["queue"].(string)
+		death.Reason, _ = table["reason"].(string)
+		death.Exchange, _ = table["exchange"].(string)
+		death.Count, _ = table["count"].(int64)
+		death.Time, _ = table["time"].(time.Time)
+		keys, _ := table["routing-keys"].([]interface{})
+		for _, key := range keys {
+			if value, ok := key.(string); ok {
+				death.RoutingKeys = append(death.RoutingKeys, value)
+			}
+		}
+		deaths = append(deaths, death)
+	}
+	return deaths
+}
+
+// QueueBrowser holds messages fetched from a queue without acknowledging
+// them. Messages not acknowledged go back to the queue on Close.
+type QueueBrowser interface {
+	// Messages returns the fetched messages in queue order
+	Messages() []amqp.Delivery
+	// Ack removes the message at index i from the queue
+	Ack(i int) error
+	// Republish sends the message at index i back to its original exchange
+	// and routing key and removes it from the queue
+	Republish(ctx context.Context, i int) error
+	Close()
+}
+
+// Browser reads and purges the messages of queues. Modules receive a Browser
+// instead of calling the package functions so they can be exercised with a
+// FakeBroker.
+type Browser interface {
+	// Browse fetches up to limit messages from queue, or all of them when
+	// limit is zero
+	Browse(queue string, limit int) (QueueBrowser, error)
+	// Purge removes every message of queue and returns how many were removed
+	Purge(queue string) (int, error)
+}
+
+// DefaultBrowser is the Browser backed by DefaultBroker.
+var DefaultBrowser Browser = defaultBrowser{}
+
+type defaultBrowser struct {
+}
+
+func (defaultBrowser) Browse(queue string, limit int) (QueueBrowser, error) {
+	return DefaultBroker().Browse(queue, limit)
+}
+
+func (defaultBrowser) Purge(queue string) (int, error) {
+	return DefaultBroker().Purge(queue)
+}
+
+type queueBrowser struct {
+	messages []amqp.Delivery
+	broker   *Broker
+	channel  *amqp.Channel
+}
+
+func (b *Broker) Browse(queue string, limit int) (QueueBrowser, error) {
+	ch, err := b.Channel()
+	if err != nil {
+		return nil, err
+	}
+	browser := &queueBrowser{broker: b, channel: ch}
+	for limit <= 0 || len(browser.messages) < limit {
+		d, ok, err := ch.Get(queue, false)
+		if err != nil {
+			b.Release(ch, true)
+			return nil, channelError("get", err)
+		}
+		if !ok {
+			break
+		}
+		browser.messages = append(browser.messages, d)
+	}
+	return browser, nil
+}
+
+func Browse(queue string, limit int) (QueueBrowser, error) {
+	return DefaultBroker().Browse(queue, limit)
+}
+
+func (qb *queueBrowser) Messages() []amqp.Delivery {
+	return qb.messages
+}
+
+func (qb *queueBrowser) Ack(i int) error {
+	return channelError("ack", qb.channel.Ack(qb.messages[i].DeliveryTag, false))
+}
+
+// Republish uses the exchange and routing key of the oldest x-death entry
+// and acknowledges the message once the broker confirms it.
+func (qb *queueBrowser) Republish(ctx context.Context, i int) error {
+	exchange, routingKey, msg, err := republishing(&qb.messages[i])
+	if err != nil {
+		return fmt.Errorf("message %d %s", i+1, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+	if err := qb.broker.publish(ctx, qb.channel, exchange, routingKey, msg); err != nil {
+		return err
+	}
+	return qb.Ack(i)
+}
+
+// republishing returns the origin of a dead-lettered message and a copy of it
+// to send there. The user id is dropped because RabbitMQ closes the channel
+// when it differs from the user of the connection, which is the case for
+// messages published by other services.
+func republishing(d *amqp.Delivery) (exchange string, routingKey string, msg amqp.Publishing, err error) {
+	deaths := Deaths(d.Headers)
+	if len(deaths) == 0 {
+		return "", "", msg, fmt.Errorf("has no x-death header")
+	}
+	origin := deaths[len(deaths)-1]
+	routingKey = d.RoutingKey
+	if len(origin.RoutingKeys) > 0 {
+		routingKey = origin.RoutingKeys[0]
+	}
+	msg = amqp.Publishing{
+		Headers:         d.Headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+	return origin.Exchange, routingKey, msg, nil
+}
+
+func (qb *queueBrowser) Close() {
+	// Closing the channel requeues its unacknowledged messages
+	qb.broker.Release(qb.channel, true)
+}
+
+func (b *Broker) Purge(queue string) (int, error) {
+	ch, err := b.Channel()
+	if err != nil {
+		return 0, err
+	}
+	count, err := ch.QueuePurge(queue, false)
+	b.Release(ch, err != nil)
+	return count, channelError("purge", err)
+}
+
+func Purge(queue string) (int, error) {
+	return DefaultBroker().Purge(queue)
+}
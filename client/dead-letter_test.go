@@ -0,0 +1,44 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+func TestRepublishing(t *testing.T) {
+	deaths := []interface{}{
+		amqp.Table{"queue": "customer.pull", "reason": "rejected", "exchange": "cnp.referential", "routing-keys": []interface{}{"customer.retry"}},
+		amqp.Table{"queue": "customer.pull", "reason": "expired", "exchange": "cnp.referential", "routing-keys": []interface{}{"customer.pull"}},
+	}
+	d := amqp.Delivery{
+		Headers:       amqp.Table{"x-death": deaths, "App-Username": "demo"},
+		RoutingKey:    "customer.pull.dlq",
+		ContentType:   "application/json",
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: "c1",
+		MessageId:     "m1",
+		UserId:        "cnp-customers",
+		AppId:         "cnp-customers",
+		Body:          []byte(`{"id":"1"}`),
+	}
+	exchange, routingKey, msg, err := republishing(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exchange != "cnp.referential" || routingKey != "customer.pull" {
+		t.Errorf("unexpected origin %s %s", exchange, routingKey)
+	}
+	// A foreign user id would make the broker close the channel
+	if msg.UserId != "" {
+		t.Errorf("user id %s copied", msg.UserId)
+	}
+	if msg.CorrelationId != "c1" || msg.MessageId != "m1" || msg.AppId != "cnp-customers" || msg.ContentType != "application/json" ||
+		msg.DeliveryMode != amqp.Persistent || string(msg.Body) != `{"id":"1"}` || msg.Headers["App-Username"] != "demo" {
+		t.Errorf("properties not copied: %+v", msg)
+	}
+
+	if _, _, _, err := republishing(&amqp.Delivery{RoutingKey: "customer.pull"}); err == nil {
+		t.Error("message without x-death republished")
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/streadway/amqp"
+)
+
+// Sentinel errors describing why a client operation failed. Every error
+// returned by this package matches one of them with errors.Is.
+var (
+	ErrConfiguration     = errors.New("invalid broker configuration")
+	ErrConnectionRefused = errors.New("broker connection refused")
+	ErrAuthentication    = errors.New("broker authentication failed")
+	ErrChannel           = errors.New("broker channel error")
+	ErrReplyTimeout      = errors.New("reply not received in time")
+	ErrUnroutable        = errors.New("message not routed by the broker")
+	ErrNacked            = errors.New("message rejected by the broker")
+	ErrInvalidSignature  = errors.New("invalid message signature")
+	ErrNotFound          = errors.New("resource not found in the broker")
+	ErrManagement        = errors.New("management api request failed")
+)
+
+// Error wraps the underlying driver error with the operation that failed and
+// the sentinel error it is classified as.
+type Error struct {
+	Kind error
+	Op   string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s: %s", e.Op, e.Kind)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Op, e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// UnroutableError is returned when the broker gives a mandatory message back
+// because no queue is bound to the exchange for its routing key.
+type UnroutableError struct {
+	Exchange   string
+	RoutingKey string
+	Reason     string
+}
+
+func (e *UnroutableError) Error() string {
+	return fmt.Sprintf("message to exchange '%s' with routing key '%s' was not routed: %s", e.Exchange, e.RoutingKey, e.Reason)
+}
+
+func (e *UnroutableError) Is(target error) bool {
+	return target == ErrUnroutable
+}
+
+// dialError classifies a failed connection to uri. Invalid URIs and TLS
+// certificate or handshake failures are configuration errors, which are not
+// retried, unlike a broker that is down.
+func dialError(uri string, err error) error {
+	var amqpErr *amqp.Error
+	if _, uriErr := amqp.ParseURI(uri); uriErr != nil {
+		return &Error{Kind: ErrConfiguration, Op: "parse uri", Err: uriErr}
+	} else if isTLSError(err) {
+		return &Error{Kind: ErrConfiguration, Op: "tls handshake", Err: err}
+	} else if err == amqp.ErrCredentials || err == amqp.ErrSASL {
+		return &Error{Kind: ErrAuthentication, Op: "dial", Err: err}
+	} else if errors.As(err, &amqpErr) && amqpErr.Code == amqp.AccessRefused {
+		return &Error{Kind: ErrAuthentication, Op: "dial", Err: err}
+	}
+	return &Error{Kind: ErrConnectionRefused, Op: "dial", Err: err}
+}
+
+func isTLSError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var verification *tls.CertificateVerificationError
+	var record tls.RecordHeaderError
+	var alert tls.AlertError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid) ||
+		errors.As(err, &verification) || errors.As(err, &record) || errors.As(err, &alert)
+}
+
+func channelError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var clientErr *Error
+	if errors.As(err, &clientErr) {
+		return err
+	}
+	return &Error{Kind: ErrChannel, Op: op, Err: err}
+}
+
+// contextError reports an expired deadline as ErrReplyTimeout and keeps
+// explicit cancellations as they are.
+func contextError(op string, err error) error {
+	if err == context.DeadlineExceeded {
+		return &Error{Kind: ErrReplyTimeout, Op: op, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// Message is a message captured by FakeBroker.
+type Message struct {
+	Exchange   string
+	RoutingKey string
+	Headers    amqp.Table
+	Body       string
+	Request    bool
+}
+
+// FakeBroker is an in-memory Publisher that records every message and answers
+// requests with the replies programmed with Reply. Requests without a reply
+// fail with ErrReplyTimeout, like a service that is down. It is also a
+// Browser of the queues filled with Enqueue.
+type FakeBroker struct {
+	mutex    sync.Mutex
+	Messages []Message
+	replies  map[string][]string
+	errors   map[string]error
+	queues   map[string][]amqp.Delivery
+}
+
+func NewFakeBroker() *FakeBroker {
+	return &FakeBroker{
+		replies: make(map[string][]string),
+		errors:  make(map[string]error),
+		queues:  make(map[string][]amqp.Delivery),
+	}
+}
+
+// Reply programs the answers to requests sent to exchange and routingKey.
+// SendAndReceive returns the first one and SendAndCollect all of them.
+func (f *FakeBroker) Reply(exchange string, routingKey string, replies ...string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.replies[exchange+"/"+routingKey] = replies
+}
+
+// Fail makes every message sent to exchange and routingKey return err.
+func (f *FakeBroker) Fail(exchange string, routingKey string, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.errors[exchange+"/"+routingKey] = err
+}
+
+// Last returns the last captured message, or nil if nothing was sent.
+func (f *FakeBroker) Last() *Message {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if len(f.Messages) == 0 {
+		return nil
+	}
+	return &f.Messages[len(f.Messages)-1]
+}
+
+func (f *FakeBroker) SendMessageWithHeaders(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.Messages = append(f.Messages, Message{Exchange: exchange, RoutingKey: routingKey, Headers: headers, Body: body})
+	return f.errors[exchange+"/"+routingKey]
+}
+
+func (f *FakeBroker) SendAndReceive(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) (string, error) {
+	replies, err := f.SendAndCollect(ctx, exchange, routingKey, body, headers, CollectOptions{Count: 1}, verbose)
+	if err != nil {
+		return "", err
+	}
+	return replies[0], nil
+}
+
+func (f *FakeBroker) SendAndCollect(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, collect CollectOptions, verbose bool) ([]string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.Messages = append(f.Messages, Message{Exchange: exchange, RoutingKey: routingKey, Headers: headers, Body: body, Request: true})
+	key := exchange + "/" + routingKey
+	if err := f.errors[key]; err != nil {
+		return nil, err
+	}
+	replies := f.replies[key]
+	if len(replies) == 0 {
+		return nil, &Error{Kind: ErrReplyTimeout, Op: "wait reply"}
+	}
+	if collect.Count > 0 && len(replies) > collect.Count {
+		replies = replies[:collect.Count]
+	}
+	return replies, nil
+}
+
+// Enqueue appends messages to a queue read by Browse.
+func (f *FakeBroker) Enqueue(queue string, messages ...amqp.Delivery) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.queues[queue] = append(f.queues[queue], messages...)
+}
+
+// Queue returns the messages left in a queue.
+func (f *FakeBroker) Queue(queue string) []amqp.Delivery {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]amqp.Delivery(nil), f.queues[queue]...)
+}
+
+func (f *FakeBroker) Browse(queue string, limit int) (QueueBrowser, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	messages := f.queues[queue]
+	if limit > 0 && len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return &fakeQueueBrowser{broker: f, queue: queue, messages: append([]amqp.Delivery(nil), messages...), acked: make(map[int]bool)}, nil
+}
+
+func (f *FakeBroker) Purge(queue string) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	count := len(f.queues[queue])
+	delete(f.queues, queue)
+	return count, nil
+}
+
+// fakeQueueBrowser removes the acknowledged messages from the queue on Close.
+type fakeQueueBrowser struct {
+	broker   *FakeBroker
+	queue    string
+	messages []amqp.Delivery
+	acked    map[int]bool
+}
+
+func (b *fakeQueueBrowser) Messages() []amqp.Delivery {
+	return b.messages
+}
+
+func (b *fakeQueueBrowser) Ack(i int) error {
+	b.acked[i] = true
+	return nil
+}
+
+func (b *fakeQueueBrowser) Republish(ctx context.Context, i int) error {
+	exchange, routingKey, msg, err := republishing(&b.messages[i])
+	if err != nil {
+		return err
+	}
+	if err := b.broker.SendMessageWithHeaders(ctx, exchange, routingKey, string(msg.Body), msg.Headers, false); err != nil {
+		return err
+	}
+	return b.Ack(i)
+}
+
+func (b *fakeQueueBrowser) Close() {
+	b.broker.mutex.Lock()
+	defer b.broker.mutex.Unlock()
+	var left []amqp.Delivery
+	for i, d := range b.messages {
+		if !b.acked[i] {
+			left = append(left, d)
+		}
+	}
+	b.broker.queues[b.queue] = append(left, b.broker.queues[b.queue][len(b.messages):]...)
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestManagementErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/exchanges/vhost/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/api/exchanges/vhost/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"name":"cnp.sepa","type":"topic","durable":true}`))
+		}
+	}))
+	defer server.Close()
+	m := &Management{BaseUrl: server.URL, Vhost: "vhost"}
+
+	if info, err := m.Exchange("cnp.sepa"); err != nil || info.Type != "topic" {
+		t.Errorf("unexpected exchange %+v: %v", info, err)
+	}
+	if _, err := m.Exchange("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if _, err := m.Exchange("broken"); !errors.Is(err, ErrManagement) {
+		t.Errorf("expected management error, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// MessageIdAuto generates a new UUID message id for every message.
+const MessageIdAuto = "auto"
+
+// MessageOptions overrides the properties of every message sent by the
+// client. Zero values keep the defaults of each kind of message.
+type MessageOptions struct {
+	Priority     int
+	Expiration   time.Duration
+	DeliveryMode int
+	ContentType  string
+	MessageId    string
+	AppId        string
+	// Variables that could not be parsed, reported when a message is sent
+	invalid []string
+}
+
+// MessageOptionsFromEnv reads the APP_AMQP_PRIORITY, APP_AMQP_EXPIRATION,
+// APP_AMQP_DELIVERY_MODE, APP_AMQP_CONTENT_TYPE, APP_AMQP_MESSAGE_ID and
+// APP_AMQP_APP_ID variables.
+func MessageOptionsFromEnv() MessageOptions {
+	options := MessageOptions{
+		ContentType: os.Getenv("APP_AMQP_CONTENT_TYPE"),
+		MessageId:   os.Getenv("APP_AMQP_MESSAGE_ID"),
+		AppId:       os.Getenv("APP_AMQP_APP_ID"),
+	}
+	var err error
+	if value := os.Getenv("APP_AMQP_PRIORITY"); value != "" {
+		if options.Priority, err = strconv.Atoi(value); err != nil {
+			options.invalid = append(options.invalid, "APP_AMQP_PRIORITY="+value)
+		}
+	}
+	if value := os.Getenv("APP_AMQP_DELIVERY_MODE"); value != "" {
+		if options.DeliveryMode, err = strconv.Atoi(value); err != nil {
+			options.invalid = append(options.invalid, "APP_AMQP_DELIVERY_MODE="+value)
+		}
+	}
+	if value := os.Getenv("APP_AMQP_EXPIRATION"); value != "" {
+		if options.Expiration, err = time.ParseDuration(value); err != nil {
+			options.invalid = append(options.invalid, "APP_AMQP_EXPIRATION="+value)
+		}
+	}
+	return options
+}
+
+// Validate returns an ErrConfiguration error for values that cannot be sent.
+func (o MessageOptions) Validate() error {
+	var err error
+	switch {
+	case len(o.invalid) > 0:
+		err = fmt.Errorf("invalid %s", strings.Join(o.invalid, ", "))
+	case o.Priority < 0 || o.Priority > 255:
+		err = fmt.Errorf("priority %d out of range 1-255", o.Priority)
+	case o.Expiration < 0:
+		err = fmt.Errorf("negative expiration %s", o.Expiration)
+	case o.DeliveryMode != 0 && o.DeliveryMode != int(amqp.Transient) && o.DeliveryMode != int(amqp.Persistent):
+		err = fmt.Errorf("delivery mode %d is neither 1 (transient) nor 2 (persistent)", o.DeliveryMode)
+	}
+	if err != nil {
+		return &Error{Kind: ErrConfiguration, Op: "message options", Err: err}
+	}
+	return nil
+}
+
+func (o MessageOptions) apply(msg *amqp.Publishing) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	if o.Priority > 0 {
+		msg.Priority = uint8(o.Priority)
+	}
+	if o.Expiration > 0 {
+		// Expirations below a millisecond would be sent as 0, which expires
+		// the message at once
+		expiration := (o.Expiration + time.Millisecond - 1) / time.Millisecond
+		msg.Expiration = strconv.FormatInt(int64(expiration), 10)
+	}
+	if o.DeliveryMode != 0 {
+		msg.DeliveryMode = uint8(o.DeliveryMode)
+	}
+	if o.ContentType != "" {
+		msg.ContentType = o.ContentType
+	}
+	if o.MessageId == MessageIdAuto {
+		msg.MessageId = NewCorrelationId()
+	} else if o.MessageId != "" {
+		msg.MessageId = o.MessageId
+	}
+	if o.AppId != "" {
+		msg.AppId = o.AppId
+	}
+	return nil
+}
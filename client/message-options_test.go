@@ -0,0 +1,82 @@
+package client
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+func TestMessageOptionsApply(t *testing.T) {
+	defaults := amqp.Publishing{
+		DeliveryMode: amqp.Persistent,
+		ContentType:  "text/plain",
+		Headers:      amqp.Table{"App-Username": "test"},
+	}
+	tests := []struct {
+		name     string
+		options  MessageOptions
+		expected amqp.Publishing
+		err      bool
+	}{
+		{"defaults", MessageOptions{}, defaults, false},
+		{"expiration", MessageOptions{Expiration: 90 * time.Second}, amqp.Publishing{DeliveryMode: amqp.Persistent, ContentType: "text/plain", Headers: amqp.Table{"App-Username": "test"}, Expiration: "90000"}, false},
+		{"expiration rounded up", MessageOptions{Expiration: 1500 * time.Microsecond}, amqp.Publishing{DeliveryMode: amqp.Persistent, ContentType: "text/plain", Headers: amqp.Table{"App-Username": "test"}, Expiration: "2"}, false},
+		{"expiration below a millisecond", MessageOptions{Expiration: time.Microsecond}, amqp.Publishing{DeliveryMode: amqp.Persistent, ContentType: "text/plain", Headers: amqp.Table{"App-Username": "test"}, Expiration: "1"}, false},
+		{"negative expiration", MessageOptions{Expiration: -time.Second}, defaults, true},
+		{"priority", MessageOptions{Priority: 5}, amqp.Publishing{DeliveryMode: amqp.Persistent, ContentType: "text/plain", Headers: amqp.Table{"App-Username": "test"}, Priority: 5}, false},
+		{"max priority", MessageOptions{Priority: 255}, amqp.Publishing{DeliveryMode: amqp.Persistent, ContentType: "text/plain", Headers: amqp.Table{"App-Username": "test"}, Priority: 255}, false},
+		{"priority out of range", MessageOptions{Priority: 300}, defaults, true},
+		{"negative priority", MessageOptions{Priority: -1}, defaults, true},
+		{"transient", MessageOptions{DeliveryMode: 1}, amqp.Publishing{DeliveryMode: amqp.Transient, ContentType: "text/plain", Headers: amqp.Table{"App-Username": "test"}}, false},
+		{"persistent", MessageOptions{DeliveryMode: 2}, defaults, false},
+		{"invalid delivery mode", MessageOptions{DeliveryMode: 3}, defaults, true},
+		{"properties", MessageOptions{ContentType: "application/json", MessageId: "m1", AppId: "hodei"}, amqp.Publishing{DeliveryMode: amqp.Persistent, ContentType: "application/json", Headers: amqp.Table{"App-Username": "test"}, MessageId: "m1", AppId: "hodei"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg := defaults
+			msg.Headers = amqp.Table{"App-Username": "test"}
+			err := test.options.apply(&msg)
+			if test.err != errors.Is(err, ErrConfiguration) {
+				t.Errorf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(msg, test.expected) {
+				t.Errorf("got %+v, want %+v", msg, test.expected)
+			}
+		})
+	}
+}
+
+func TestMessageOptionsAutoId(t *testing.T) {
+	var first, second amqp.Publishing
+	MessageOptions{MessageId: MessageIdAuto}.apply(&first)
+	MessageOptions{MessageId: MessageIdAuto}.apply(&second)
+	if first.MessageId == "" || first.MessageId == MessageIdAuto || first.MessageId == second.MessageId {
+		t.Errorf("expected new message ids, got '%s' and '%s'", first.MessageId, second.MessageId)
+	}
+}
+
+func TestMessageOptionsFromEnv(t *testing.T) {
+	for _, name := range []string{"APP_AMQP_PRIORITY", "APP_AMQP_DELIVERY_MODE", "APP_AMQP_EXPIRATION"} {
+		defer os.Setenv(name, os.Getenv(name))
+	}
+	os.Setenv("APP_AMQP_PRIORITY", "5")
+	os.Setenv("APP_AMQP_DELIVERY_MODE", "1")
+	os.Setenv("APP_AMQP_EXPIRATION", "30s")
+	options := MessageOptionsFromEnv()
+	if err := options.Validate(); err != nil || options.Priority != 5 || options.DeliveryMode != 1 || options.Expiration != 30*time.Second {
+		t.Errorf("unexpected options %+v, %v", options, err)
+	}
+
+	os.Setenv("APP_AMQP_PRIORITY", "high")
+	os.Setenv("APP_AMQP_EXPIRATION", "30")
+	if err := MessageOptionsFromEnv().Validate(); !errors.Is(err, ErrConfiguration) {
+		t.Errorf("unexpected error %v", err)
+	} else if err.Error() != "message options: invalid broker configuration: invalid APP_AMQP_PRIORITY=high, APP_AMQP_EXPIRATION=30" {
+		t.Errorf("unexpected message %s", err)
+	}
+}
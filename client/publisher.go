@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+)
+
+// Publisher sends fire-and-forget and request/reply messages to the platform.
+// Modules receive a Publisher instead of calling the package functions so
+// they can be exercised with a FakeBroker.
+type Publisher interface {
+	SendMessageWithHeaders(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) error
+	SendAndReceive(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) (string, error)
+	SendAndCollect(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, collect CollectOptions, verbose bool) ([]string, error)
+}
+
+// Default is the Publisher backed by DefaultBroker. The broker is resolved on
+// every call so that flags parsed after startup are honored.
+var Default Publisher = defaultPublisher{}
+
+type defaultPublisher struct {
+}
+
+func (defaultPublisher) SendMessageWithHeaders(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) error {
+	return DefaultBroker().SendMessageWithHeaders(ctx, exchange, routingKey, body, headers, verbose)
+}
+
+func (defaultPublisher) SendAndReceive(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, verbose bool) (string, error) {
+	return DefaultBroker().SendAndReceive(ctx, exchange, routingKey, body, headers, verbose)
+}
+
+func (defaultPublisher) SendAndCollect(ctx context.Context, exchange string, routingKey string, body string, headers amqp.Table, collect CollectOptions, verbose bool) ([]string, error) {
+	return DefaultBroker().SendAndCollect(ctx, exchange, routingKey, body, headers, collect, verbose)
+}
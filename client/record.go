@@ -0,0 +1,124 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	RecordPublish = "publish"
+	RecordRequest = "request"
+)
+
+// Record is a single message sent through the client, as written to the
+// -record file in JSON lines format. It holds the message given by the
+// command, before the exchange renames, the message options and the trace
+// and signature headers are applied, so a replay applies them again for the
+// environment it runs against instead of resending stale signatures.
+type Record struct {
+	Type          string                 `json:"type"`
+	Time          time.Time              `json:"time"`
+	Exchange      string                 `json:"exchange"`
+	RoutingKey    string                 `json:"routingKey"`
+	Headers       map[string]interface{} `json:"headers,omitempty"`
+	Body          string                 `json:"body"`
+	CorrelationId string                 `json:"correlationId,omitempty"`
+	Reply         string                 `json:"reply,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	DurationMs    int64                  `json:"durationMs"`
+	// Collect is set for requests whose replies were all collected, which
+	// are joined in Reply with new lines
+	Collect *CollectOptions `json:"collect,omitempty"`
+}
+
+type Recorder struct {
+	mutex   sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// OpenRecorder appends records to the given file, creating it if needed.
+func OpenRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+func (r *Recorder) Write(record Record) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.encoder.Encode(record)
+}
+
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.file.Close()
+}
+
+func ReadRecords(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// HeadersTable converts recorded headers back into an AMQP table.
+func (r Record) HeadersTable() amqp.Table {
+	if r.Headers == nil {
+		return nil
+	}
+	return amqp.Table(r.Headers)
+}
+
+// record writes the message to the configured record file. Failures to
+// record are logged and never fail the message itself.
+func (b *Broker) record(record Record, err error, started time.Time) {
+	if b.config.Record == "" {
+		return
+	}
+	b.mutex.Lock()
+	if b.recorder == nil {
+		recorder, openErr := OpenRecorder(b.config.Record)
+		if openErr != nil {
+			b.mutex.Unlock()
+			log.Printf("Error opening record file: %s", openErr)
+			return
+		}
+		b.recorder = recorder
+	}
+	recorder := b.recorder
+	b.mutex.Unlock()
+
+	record.Time = started
+	record.DurationMs = time.Since(started).Nanoseconds() / int64(time.Millisecond)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if writeErr := recorder.Write(record); writeErr != nil {
+		log.Printf("Error recording message: %s", writeErr)
+	}
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// RetryPolicy controls how many times an operation is attempted when the
+// broker fails with a transient error and how long to wait between attempts.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     500 * time.Millisecond,
+	MaxBackoff:  10 * time.Second,
+}
+
+// RetryPolicyFromEnv reads APP_AMQP_RETRY_ATTEMPTS and APP_AMQP_RETRY_BACKOFF
+// falling back to DefaultRetryPolicy.
+func RetryPolicyFromEnv() RetryPolicy {
+	policy := DefaultRetryPolicy
+	if value, err := strconv.Atoi(os.Getenv("APP_AMQP_RETRY_ATTEMPTS")); err == nil {
+		policy.MaxAttempts = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("APP_AMQP_RETRY_BACKOFF")); err == nil {
+		policy.Backoff = value
+	}
+	return policy
+}
+
+// Delay returns the exponential backoff with full jitter to wait after the
+// given failed attempt, starting at 1.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)))
+}
+
+// Retryable reports whether err is caused by a transient broker failure, such
+// as a refused connection or a connection closed by the server, after which
+// the operation can be attempted again on a new connection.
+func Retryable(err error) bool {
+	if errors.Is(err, ErrConnectionRefused) {
+		return true
+	}
+	if !errors.Is(err, ErrChannel) {
+		return false
+	}
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) {
+		switch amqpErr.Code {
+		case amqp.ConnectionForced, amqp.InternalError, amqp.ResourceError, amqp.ChannelError, amqp.FrameError:
+			return true
+		}
+		return false
+	}
+	return errors.Is(err, amqp.ErrClosed)
+}
+
+func (b *Broker) retry(ctx context.Context, verbose bool, fn func() error) (err error) {
+	policy := b.config.Retry
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !Retryable(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+		delay := policy.Delay(attempt)
+		if verbose {
+			log.Printf("Attempt %d failed (%s), retrying in %s", attempt, err, delay)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
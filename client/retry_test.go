@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		limit   time.Duration
+	}{
+		{"first attempt", RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}, 1, 100 * time.Millisecond},
+		{"second attempt doubles", RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}, 2, 200 * time.Millisecond},
+		{"third attempt doubles", RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}, 3, 400 * time.Millisecond},
+		{"capped", RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}, 10, 300 * time.Millisecond},
+		{"backoff above max", RetryPolicy{Backoff: time.Second, MaxBackoff: 300 * time.Millisecond}, 1, 300 * time.Millisecond},
+		{"no max", RetryPolicy{Backoff: 100 * time.Millisecond}, 4, 800 * time.Millisecond},
+		{"no backoff", RetryPolicy{MaxBackoff: time.Second}, 3, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var max time.Duration
+			for i := 0; i < 200; i++ {
+				delay := test.policy.Delay(test.attempt)
+				if delay < 0 || (test.limit == 0 && delay != 0) || (test.limit > 0 && delay >= test.limit) {
+					t.Fatalf("delay %s out of [0, %s)", delay, test.limit)
+				}
+				if delay > max {
+					max = delay
+				}
+			}
+			// Full jitter spreads the delays over the whole range
+			if test.limit > 0 && max < test.limit/2 {
+				t.Errorf("max delay %s, expected jitter up to %s", max, test.limit)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"connection refused", dialError("amqp://localhost", errors.New("dial tcp: connection refused")), true},
+		{"authentication", dialError("amqp://localhost", amqp.ErrCredentials), false},
+		{"invalid uri", dialError("amqp://localhost:port", errors.New("dial")), false},
+		{"tls", dialError("amqps://localhost", x509.UnknownAuthorityError{}), false},
+		{"connection forced", channelError("publish", &amqp.Error{Code: amqp.ConnectionForced}), true},
+		{"internal error", channelError("publish", &amqp.Error{Code: amqp.InternalError}), true},
+		{"resource error", channelError("publish", &amqp.Error{Code: amqp.ResourceError}), true},
+		{"channel error", channelError("publish", &amqp.Error{Code: amqp.ChannelError}), true},
+		{"frame error", channelError("publish", &amqp.Error{Code: amqp.FrameError}), true},
+		{"channel closed", channelError("publish", amqp.ErrClosed), true},
+		{"wrapped", fmt.Errorf("send: %w", channelError("publish", amqp.ErrClosed)), true},
+		{"not found", channelError("publish", &amqp.Error{Code: amqp.NotFound}), false},
+		{"access refused", channelError("publish", &amqp.Error{Code: amqp.AccessRefused}), false},
+		{"other channel error", channelError("publish", errors.New("boom")), false},
+		{"reply timeout", contextError("wait reply", context.DeadlineExceeded), false},
+		{"cancelled", contextError("wait reply", context.Canceled), false},
+		{"unroutable", &UnroutableError{Exchange: "e", RoutingKey: "k"}, false},
+		{"nacked", &Error{Kind: ErrNacked, Op: "publish"}, false},
+		{"closed without channel error", amqp.ErrClosed, false},
+		{"plain error", errors.New("boom"), false},
+		{"nil", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Retryable(test.err); got != test.retryable {
+				t.Errorf("Retryable(%v) = %v, want %v", test.err, got, test.retryable)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// SignatureHeader holds the HMAC of signed messages with the key id, the
+// signing time and the signed headers, for example
+// keyId=ops;timestamp=1700000000;headers=App-Username,App-Authorities;signature=...
+const SignatureHeader = "App-Signature"
+
+// DefaultSignedHeaders are the headers signed when none are configured.
+var DefaultSignedHeaders = []string{"App-Username", "App-Authorities"}
+
+// SigningOptions configures the HMAC-SHA256 signature of every message sent
+// by the client. Messages are only signed when a key is defined.
+type SigningOptions struct {
+	Key     string
+	KeyId   string
+	Headers []string
+}
+
+// Signature is the content of a SignatureHeader.
+type Signature struct {
+	KeyId     string
+	Timestamp time.Time
+	Headers   []string
+	Value     string
+}
+
+// SigningOptionsFromEnv reads the APP_AMQP_SIGNING_KEY, APP_AMQP_SIGNING_KEY_ID
+// and APP_AMQP_SIGNING_HEADERS variables. Headers are separated by commas.
+func SigningOptionsFromEnv() SigningOptions {
+	options := SigningOptions{
+		Key:     os.Getenv("APP_AMQP_SIGNING_KEY"),
+		KeyId:   os.Getenv("APP_AMQP_SIGNING_KEY_ID"),
+		Headers: DefaultSignedHeaders,
+	}
+	if headers := os.Getenv("APP_AMQP_SIGNING_HEADERS"); headers != "" {
+		options.Headers = SplitHeaders(headers)
+	}
+	return options
+}
+
+// SplitHeaders parses a comma separated list of header names.
+func SplitHeaders(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// Sign returns a copy of headers with the SignatureHeader of the message.
+func (o SigningOptions) Sign(headers amqp.Table, body []byte, now time.Time) amqp.Table {
+	res := amqp.Table{}
+	for k, v := range headers {
+		res[k] = v
+	}
+	delete(res, SignatureHeader)
+	signature := Signature{KeyId: o.KeyId, Timestamp: time.Unix(now.Unix(), 0), Headers: o.Headers}
+	signature.Value = o.hmac(signature, res, body)
+	res[SignatureHeader] = signature.String()
+	return res
+}
+
+// Verify checks the SignatureHeader of a message, which must cover at least
+// the configured Headers. Signatures older than maxAge are rejected unless
+// maxAge is zero.
+func (o SigningOptions) Verify(headers amqp.Table, body []byte, maxAge time.Duration, now time.Time) (*Signature, error) {
+	value, ok := headers[SignatureHeader]
+	if !ok {
+		return nil, signatureError(fmt.Errorf("missing %s header", SignatureHeader))
+	}
+	signature, err := ParseSignature(fmt.Sprint(value))
+	if err != nil {
+		return nil, signatureError(err)
+	}
+	if o.KeyId != "" && signature.KeyId != o.KeyId {
+		return signature, signatureError(fmt.Errorf("unknown key id '%s'", signature.KeyId))
+	}
+	for _, header := range o.Headers {
+		if !containsHeader(signature.Headers, header) {
+			return signature, signatureError(fmt.Errorf("header %s is not signed", header))
+		}
+	}
+	expected := o.hmac(*signature, headers, body)
+	if !hmac.Equal([]byte(expected), []byte(signature.Value)) {
+		return signature, signatureError(fmt.Errorf("signature does not match the message"))
+	}
+	if age := now.Sub(signature.Timestamp); maxAge > 0 && (age > maxAge || age < -maxAge) {
+		return signature, signatureError(fmt.Errorf("signed %s ago", age.Round(time.Second)))
+	}
+	return signature, nil
+}
+
+// hmac signs the timestamp, the signed headers as name:value lines and the
+// body. Missing headers are signed as a name line without colon, so adding an
+// empty header breaks the signature.
+func (o SigningOptions) hmac(signature Signature, headers amqp.Table, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(o.Key))
+	fmt.Fprintf(mac, "%d\n", signature.Timestamp.Unix())
+	for _, header := range signature.Headers {
+		if value, ok := headers[header]; ok {
+			fmt.Fprintf(mac, "%s:%v\n", header, value)
+		} else {
+			fmt.Fprintf(mac, "%s\n", header)
+		}
+	}
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (o SigningOptions) sign(msg *amqp.Publishing) {
+	if o.Key != "" {
+		msg.Headers = o.Sign(msg.Headers, msg.Body, time.Now())
+	}
+}
+
+func (s Signature) String() string {
+	return fmt.Sprintf("keyId=%s;timestamp=%d;headers=%s;signature=%s",
+		s.KeyId, s.Timestamp.Unix(), strings.Join(s.Headers, ","), s.Value)
+}
+
+// ParseSignature reads the value of a SignatureHeader.
+func ParseSignature(value string) (*Signature, error) {
+	signature := &Signature{}
+	for _, field := range strings.Split(value, ";") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid signature field '%s'", field)
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "keyId":
+			signature.KeyId = kv[1]
+		case "timestamp":
+			seconds, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid signature timestamp '%s'", kv[1])
+			}
+			signature.Timestamp = time.Unix(seconds, 0)
+		case "headers":
+			signature.Headers = SplitHeaders(kv[1])
+		case "signature":
+			signature.Value = kv[1]
+		}
+	}
+	if signature.Value == "" || signature.Timestamp.IsZero() {
+		return nil, fmt.Errorf("incomplete signature '%s'", value)
+	}
+	return signature, nil
+}
+
+func containsHeader(headers []string, header string) bool {
+	for _, h := range headers {
+		if h == header {
+			return true
+		}
+	}
+	return false
+}
+
+func signatureError(err error) error {
+	return &Error{Kind: ErrInvalidSignature, Op: "verify signature", Err: err}
+}
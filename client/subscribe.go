@@ -0,0 +1,66 @@
+package client
+
+import (
+	"github.com/streadway/amqp"
+)
+
+// Subscription is a temporary exclusive queue bound to an exchange whose
+// messages are delivered on Messages until Close is called.
+type Subscription struct {
+	Queue    string
+	Messages <-chan amqp.Delivery
+	broker   *Broker
+	channel  *amqp.Channel
+}
+
+// Subscribe binds a new server named queue to the exchange for every routing
+// key in patterns and starts consuming it with automatic acknowledgements.
+func (b *Broker) Subscribe(exchange string, patterns ...string) (*Subscription, error) {
+	ch, err := b.Channel()
+	if err != nil {
+		return nil, err
+	}
+	q, err := ch.QueueDeclare(
+		"",    // name
+		false, // durable
+		true,  // delete when usused
+		true,  // exclusive
+		false, // noWait
+		nil,   // arguments
+	)
+	if err != nil {
+		b.Release(ch, true)
+		return nil, channelError("declare queue", err)
+	}
+	for _, pattern := range patterns {
+		if err = ch.QueueBind(q.Name, pattern, b.config.ExchangeName(exchange), false, nil); err != nil {
+			b.Release(ch, true)
+			return nil, channelError("bind queue", err)
+		}
+	}
+	msgs, err := ch.Consume(
+		q.Name, // queue
+		q.Name, // consumer
+		true,   // auto-ack
+		true,   // exclusive
+		false,  // no-local
+		false,  // no-wait
+		nil,    // args
+	)
+	if err != nil {
+		b.Release(ch, true)
+		return nil, channelError("consume", err)
+	}
+	return &Subscription{Queue: q.Name, Messages: msgs, broker: b, channel: ch}, nil
+}
+
+// Close cancels the consumer, which deletes the temporary queue.
+func (s *Subscription) Close() error {
+	err := s.channel.Cancel(s.Queue, false)
+	s.broker.Release(s.channel, err != nil)
+	return channelError("cancel", err)
+}
+
+func Subscribe(exchange string, patterns ...string) (*Subscription, error) {
+	return DefaultBroker().Subscribe(exchange, patterns...)
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// TLSOptions describes how to verify the broker and authenticate against it
+// when connecting with an amqps URI.
+type TLSOptions struct {
+	CACert     string
+	ClientCert string
+	ClientKey  string
+	ServerName string
+	Insecure   bool
+}
+
+func TLSOptionsFromEnv() TLSOptions {
+	insecure, _ := strconv.ParseBool(os.Getenv("APP_AMQP_TLS_INSECURE"))
+	return TLSOptions{
+		CACert:     os.Getenv("APP_AMQP_CA_CERT"),
+		ClientCert: os.Getenv("APP_AMQP_CLIENT_CERT"),
+		ClientKey:  os.Getenv("APP_AMQP_CLIENT_KEY"),
+		ServerName: os.Getenv("APP_AMQP_SERVER_NAME"),
+		Insecure:   insecure,
+	}
+}
+
+func (o TLSOptions) TLSConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.Insecure,
+	}
+	if o.CACert != "" {
+		pem, err := ioutil.ReadFile(o.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %s", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", o.CACert)
+		}
+	}
+	if o.ClientCert != "" || o.ClientKey != "" {
+		if o.ClientCert == "" || o.ClientKey == "" {
+			return nil, fmt.Errorf("client certificate and key must be configured together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %s", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
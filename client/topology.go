@@ -0,0 +1,126 @@
+package client
+
+import (
+	"errors"
+	"log"
+
+	"github.com/streadway/amqp"
+)
+
+// ExchangeExists passively declares the exchange, which fails with a
+// not-found error when it does not exist. The name is not renamed by
+// Config.Exchanges, callers resolve it with ExchangeName.
+func (b *Broker) ExchangeExists(name string) (bool, error) {
+	ch, err := b.Channel()
+	if err != nil {
+		return false, err
+	}
+	// The passive declare does not check the exchange type
+	err = ch.ExchangeDeclarePassive(name, amqp.ExchangeTopic, false, false, false, false, nil)
+	b.Release(ch, err != nil)
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+		return false, nil
+	}
+	return err == nil, channelError("declare exchange", err)
+}
+
+func ExchangeExists(name string) (bool, error) {
+	return DefaultBroker().ExchangeExists(name)
+}
+
+// Topology describes the exchanges, queues and bindings to declare on a
+// broker, as read from a topology YAML file.
+type Topology struct {
+	Exchanges []ExchangeSpec `yaml:"exchanges"`
+	Queues    []QueueSpec    `yaml:"queues"`
+	Bindings  []BindingSpec  `yaml:"bindings"`
+}
+
+type ExchangeSpec struct {
+	Name       string                 `yaml:"name"`
+	Type       string                 `yaml:"type"`
+	Durable    bool                   `yaml:"durable"`
+	AutoDelete bool                   `yaml:"autoDelete,omitempty"`
+	Arguments  map[string]interface{} `yaml:"arguments,omitempty"`
+}
+
+type QueueSpec struct {
+	Name                 string                 `yaml:"name"`
+	Durable              bool                   `yaml:"durable"`
+	AutoDelete           bool                   `yaml:"autoDelete,omitempty"`
+	DeadLetterExchange   string                 `yaml:"deadLetterExchange,omitempty"`
+	DeadLetterRoutingKey string                 `yaml:"deadLetterRoutingKey,omitempty"`
+	MessageTtl           int                    `yaml:"messageTtl,omitempty"`
+	MaxPriority          int                    `yaml:"maxPriority,omitempty"`
+	Arguments            map[string]interface{} `yaml:"arguments,omitempty"`
+}
+
+type BindingSpec struct {
+	Exchange   string `yaml:"exchange"`
+	Queue      string `yaml:"queue"`
+	RoutingKey string `yaml:"routingKey"`
+}
+
+// QueueArguments merges the dead-letter, TTL and priority settings into the
+// x-arguments of the queue declaration.
+func (q QueueSpec) QueueArguments() amqp.Table {
+	args := amqp.Table{}
+	for key, value := range q.Arguments {
+		args[key] = value
+	}
+	if q.DeadLetterExchange != "" {
+		args["x-dead-letter-exchange"] = q.DeadLetterExchange
+	}
+	if q.DeadLetterRoutingKey != "" {
+		args["x-dead-letter-routing-key"] = q.DeadLetterRoutingKey
+	}
+	if q.MessageTtl > 0 {
+		args["x-message-ttl"] = int32(q.MessageTtl)
+	}
+	if q.MaxPriority > 0 {
+		args["x-max-priority"] = int32(q.MaxPriority)
+	}
+	return args
+}
+
+// ApplyTopology declares every exchange, queue and binding. Declarations are
+// idempotent: existing objects with the same settings are left untouched and
+// objects with different settings fail with a precondition error.
+func (b *Broker) ApplyTopology(topology Topology, verbose bool) (err error) {
+	ch, err := b.Channel()
+	if err != nil {
+		return err
+	}
+	defer func() { b.Release(ch, err != nil) }()
+
+	for _, exchange := range topology.Exchanges {
+		if verbose {
+			log.Printf("Declaring exchange %s (%s)", exchange.Name, exchange.Type)
+		}
+		if err = ch.ExchangeDeclare(exchange.Name, exchange.Type, exchange.Durable, exchange.AutoDelete, false, false, amqp.Table(exchange.Arguments)); err != nil {
+			return channelError("declare exchange "+exchange.Name, err)
+		}
+	}
+	for _, queue := range topology.Queues {
+		if verbose {
+			log.Printf("Declaring queue %s", queue.Name)
+		}
+		if _, err = ch.QueueDeclare(queue.Name, queue.Durable, queue.AutoDelete, false, false, queue.QueueArguments()); err != nil {
+			return channelError("declare queue "+queue.Name, err)
+		}
+	}
+	for _, binding := range topology.Bindings {
+		if verbose {
+			log.Printf("Binding %s to %s with %s", binding.Queue, binding.Exchange, binding.RoutingKey)
+		}
+		if err = ch.QueueBind(binding.Queue, binding.RoutingKey, binding.Exchange, false, nil); err != nil {
+			return channelError("bind queue "+binding.Queue, err)
+		}
+	}
+	return nil
+}
+
+func ApplyTopology(topology Topology, verbose bool) error {
+	return DefaultBroker().ApplyTopology(topology, verbose)
+}
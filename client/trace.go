@@ -0,0 +1,134 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/streadway/amqp"
+)
+
+var traceParentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+var traceIdPattern = regexp.MustCompile(`^([0-9a-f]{16}|[0-9a-f]{32})$`)
+
+// TraceOptions configures the trace context propagated in the headers of
+// every message. Parent accepts either a W3C traceparent header or a bare
+// trace id; without it a new trace is started for each process.
+type TraceOptions struct {
+	Parent string
+	State  string
+	// Also send the X-B3-* headers understood by Zipkin and Spring Sleuth
+	B3 bool
+}
+
+// TraceOptionsFromEnv reads the APP_TRACE_PARENT, APP_TRACE_STATE and
+// APP_TRACE_B3 variables, falling back to the TRACEPARENT and TRACESTATE
+// variables used by OpenTelemetry.
+func TraceOptionsFromEnv() TraceOptions {
+	options := TraceOptions{
+		Parent: os.Getenv("APP_TRACE_PARENT"),
+		State:  os.Getenv("APP_TRACE_STATE"),
+		B3:     os.Getenv("APP_TRACE_B3") == "true",
+	}
+	if options.Parent == "" {
+		options.Parent = os.Getenv("TRACEPARENT")
+	}
+	if options.State == "" {
+		options.State = os.Getenv("TRACESTATE")
+	}
+	return options
+}
+
+// traceContext holds the trace shared by all the messages sent by a broker.
+// Each message is a new span child of the parent span, if any.
+type traceContext struct {
+	traceId      string
+	parentSpanId string
+	flags        string
+	state        string
+	b3           bool
+}
+
+func (o TraceOptions) context() (*traceContext, error) {
+	trace := &traceContext{flags: "01", state: o.State, b3: o.B3}
+	parent := strings.ToLower(strings.TrimSpace(o.Parent))
+	if m := traceParentPattern.FindStringSubmatch(parent); m != nil {
+		if m[1] == "ff" || isZero(m[2]) || isZero(m[3]) {
+			return nil, fmt.Errorf("invalid traceparent '%s'", o.Parent)
+		}
+		trace.traceId, trace.parentSpanId, trace.flags = m[2], m[3], m[4]
+	} else if traceIdPattern.MatchString(parent) && !isZero(parent) {
+		// 64 bit B3 trace ids are left padded to the W3C length
+		trace.traceId = fmt.Sprintf("%032s", parent)
+	} else if parent != "" {
+		return nil, fmt.Errorf("invalid trace parent '%s'", o.Parent)
+	} else {
+		trace.traceId = randomHex(16)
+	}
+	return trace, nil
+}
+
+// inject returns a copy of headers with the trace headers of a new span.
+func (t *traceContext) inject(headers amqp.Table) amqp.Table {
+	spanId := randomHex(8)
+	res := amqp.Table{}
+	for k, v := range headers {
+		res[k] = v
+	}
+	res["traceparent"] = fmt.Sprintf("00-%s-%s-%s", t.traceId, spanId, t.flags)
+	if t.state != "" {
+		res["tracestate"] = t.state
+	}
+	if t.b3 {
+		res["X-B3-TraceId"] = t.traceId
+		res["X-B3-SpanId"] = spanId
+		if t.parentSpanId != "" {
+			res["X-B3-ParentSpanId"] = t.parentSpanId
+		}
+		if t.sampled() {
+			res["X-B3-Sampled"] = "1"
+		} else {
+			res["X-B3-Sampled"] = "0"
+		}
+	}
+	return res
+}
+
+// sampled reports whether the sampled bit of the trace flags is set.
+func (t *traceContext) sampled() bool {
+	flags, err := hex.DecodeString(t.flags)
+	return err == nil && len(flags) == 1 && flags[0]&1 == 1
+}
+
+// traceContext returns the trace of the broker, starting it and printing
+// its id the first time a message is sent.
+func (b *Broker) traceContext() (*traceContext, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.trace != nil {
+		return b.trace, nil
+	}
+	trace, err := b.config.Trace.context()
+	if err != nil {
+		return nil, &Error{Kind: ErrConfiguration, Op: "trace context", Err: err}
+	}
+	log.Printf("Using trace id: %s", trace.traceId)
+	b.trace = trace
+	return trace, nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func isZero(id string) bool {
+	return strings.Trim(id, "0") == ""
+}
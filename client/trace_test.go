@@ -0,0 +1,117 @@
+package client
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+func TestTraceContext(t *testing.T) {
+	tests := []struct {
+		name         string
+		parent       string
+		traceId      string
+		parentSpanId string
+		flags        string
+		valid        bool
+	}{
+		{"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01", true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "00", true},
+		{"uppercase and spaces", " 00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01 ", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01", true},
+		{"future version", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01", true},
+		{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", "", "", false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "", "", "", false},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", "", "", "", false},
+		{"short span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01", "", "", "", false},
+		{"not hex", "00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", "", "", "", false},
+		{"128 bit id", "4bf92f3577b34da6a3ce929d0e0e4736", "4bf92f3577b34da6a3ce929d0e0e4736", "", "01", true},
+		{"64 bit id padded", "a3ce929d0e0e4736", "0000000000000000a3ce929d0e0e4736", "", "01", true},
+		{"zero id", "0000000000000000", "", "", "", false},
+		{"odd length id", "a3ce929d0e0e473", "", "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trace, err := TraceOptions{Parent: test.parent}.context()
+			if !test.valid {
+				if err == nil {
+					t.Fatalf("expected error for '%s'", test.parent)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if trace.traceId != test.traceId || trace.parentSpanId != test.parentSpanId || trace.flags != test.flags {
+				t.Errorf("got %s %s %s, want %s %s %s", trace.traceId, trace.parentSpanId, trace.flags, test.traceId, test.parentSpanId, test.flags)
+			}
+		})
+	}
+}
+
+func TestTraceNewId(t *testing.T) {
+	first, err := TraceOptions{}.context()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := TraceOptions{}.context()
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(first.traceId) || isZero(first.traceId) {
+		t.Errorf("invalid trace id %s", first.traceId)
+	}
+	if first.traceId == second.traceId {
+		t.Errorf("expected a new trace id, got %s twice", first.traceId)
+	}
+	if first.parentSpanId != "" || first.flags != "01" {
+		t.Errorf("unexpected parent %s, flags %s", first.parentSpanId, first.flags)
+	}
+}
+
+func TestTraceInject(t *testing.T) {
+	traceParent := regexp.MustCompile(`^00-4bf92f3577b34da6a3ce929d0e0e4736-([0-9a-f]{16})-(0[0-3])$`)
+	tests := []struct {
+		name    string
+		options TraceOptions
+		sampled string
+	}{
+		{"sampled", TraceOptions{Parent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", State: "congo=t61rcWkgMzE", B3: true}, "1"},
+		{"not sampled", TraceOptions{Parent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", B3: true}, "0"},
+		{"sampled with other flags", TraceOptions{Parent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03", B3: true}, "1"},
+		{"other flags not sampled", TraceOptions{Parent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-02", B3: true}, "0"},
+		{"without b3", TraceOptions{Parent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trace, err := test.options.context()
+			if err != nil {
+				t.Fatal(err)
+			}
+			original := amqp.Table{"App-Username": "test"}
+			headers := trace.inject(original)
+			if len(original) != 1 || headers["App-Username"] != "test" {
+				t.Errorf("headers not copied: %v", headers)
+			}
+			m := traceParent.FindStringSubmatch(headers["traceparent"].(string))
+			if m == nil {
+				t.Fatalf("unexpected traceparent %v", headers["traceparent"])
+			}
+			if m[1] == "00f067aa0ba902b7" {
+				t.Errorf("expected a new span id, got the parent one")
+			}
+			if state, ok := headers["tracestate"]; ok != (test.options.State != "") || (ok && state != test.options.State) {
+				t.Errorf("unexpected tracestate %v", state)
+			}
+			if !test.options.B3 {
+				if _, ok := headers["X-B3-TraceId"]; ok {
+					t.Errorf("unexpected b3 headers %v", headers)
+				}
+				return
+			}
+			if headers["X-B3-TraceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" || headers["X-B3-SpanId"] != m[1] || headers["X-B3-ParentSpanId"] != "00f067aa0ba902b7" {
+				t.Errorf("unexpected b3 headers %v", headers)
+			}
+			if headers["X-B3-Sampled"] != test.sampled {
+				t.Errorf("X-B3-Sampled %v, want %s", headers["X-B3-Sampled"], test.sampled)
+			}
+		})
+	}
+}
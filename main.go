@@ -1,256 +1,128 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/labcabrera/hodei-cli/client"
 	"github.com/labcabrera/hodei-cli/modules"
 )
 
 const version = "0.6.1"
 const versionCmd = "version"
+const helpCmd = "help"
 
 func main() {
-
-	if len(os.Args) < 2 {
-		usage()
-		return
-	}
-
-	cmd := os.Args[1]
-
-	var moduleMap map[string]modules.HodeiCliModule
-	moduleMap = make(map[string]modules.HodeiCliModule)
-
-	moduleMap[modules.ListScheduledActionsCmd] = modules.ListScheduledActionsModule{}
-	moduleMap[modules.MongoResetCmd] = modules.MongoResetModule{}
-
-	module, check := moduleMap[cmd]
-
-	if !check {
-		usage()
-		os.Exit(1)
-	} else {
-		module.Execute(os.Args[2:])
-		os.Exit(0)
-	}
-
-	//TODO convertir a modulos
-
-	// Parse cmd options
-	customerSearchOptions := modules.CustomerSearchOptions{}
-	customerSearchFlagSet := modules.CustomerSearchFlagSet(&customerSearchOptions)
-
-	pullCountriesOptions := modules.PullCountriesOptions{}
-	pullCountriesFlagSet := modules.PullCountriesFlagSet(&pullCountriesOptions)
-
-	pullProductsOptions := modules.PullProductsOptions{}
-	pullProductsFlagSet := modules.PullProductsFlagSet(&pullProductsOptions)
-
-	pullAgreementsOptions := modules.PullAgreementsOptions{}
-	pullAgreementsFlagSet := modules.PullAgreementsFlagSet(&pullAgreementsOptions)
-
-	pullNetworksOptions := modules.PullNetworksOptions{}
-	pullNetworksFlagSet := modules.PullNetworksFlagSet(&pullNetworksOptions)
-
-	pullCustomersOptions := modules.PullCustomerOptions{}
-	pullCustomersFlagSet := modules.PullCustomersFlagSet(&pullCustomersOptions)
-
-	pullProfessionsOptions := modules.PullProfessionsOptions{}
-	pullProfessionsFlagSet := modules.PullProfessionsFlagSet(&pullProfessionsOptions)
-
-	pullPoliciesOptions := modules.PullPoliciesOptions{}
-	pullPoliciesFlagSet := modules.PullPoliciesFlagSet(&pullPoliciesOptions)
-
-	pullOrdersOptions := modules.PullOrdersOptions{}
-	pullOrdersFlagSet := modules.PullOrdersFlagSet(&pullOrdersOptions)
-
-	pullCoveragesOptions := modules.PullCoveragesOptions{}
-	pullCoveragesFlagSet := modules.PullCoveragesFlagSet(&pullCoveragesOptions)
-
-	pullClaimsOptions := modules.PullClaimsOptions{}
-	pullClaimsFlagSet := modules.PullClaimsFlagSet(&pullClaimsOptions)
-
-	checkIbanOptions := modules.CheckIbanOptions{}
-	checkIbanFlagSet := modules.CheckIbanFlagSet(&checkIbanOptions)
-
-	signatureRequestOptions := modules.SignatureRequestOptions{}
-	signatureRequestFlagSet := modules.SignatureRequestFlagSet(&signatureRequestOptions)
-
 	rand.Seed(time.Now().UTC().UnixNano())
 
-	switch cmd {
-	case versionCmd:
-		fmt.Println("Hodei cli", version)
-		os.Exit(0)
-	case modules.CustomerSearchCmd:
-		customerSearchFlagSet.Parse(os.Args[2:])
-	case modules.PullCountriesCmd:
-		pullCountriesFlagSet.Parse(os.Args[2:])
-	case modules.PullProductsCmd:
-		pullProductsFlagSet.Parse(os.Args[2:])
-	case modules.PullAgreementsCmd:
-		pullAgreementsFlagSet.Parse(os.Args[2:])
-	case modules.PullCustomersCmd:
-		pullCustomersFlagSet.Parse(os.Args[2:])
-	case modules.PullNetworksCmd:
-		pullNetworksFlagSet.Parse(os.Args[2:])
-	case modules.PullProfessionsCmd:
-		pullProfessionsFlagSet.Parse(os.Args[2:])
-	case modules.PullPoliciesCmd:
-		pullPoliciesFlagSet.Parse(os.Args[2:])
-	case modules.PullOrdersCmd:
-		pullOrdersFlagSet.Parse(os.Args[2:])
-	case modules.PullCoveragesCmd:
-		pullCoveragesFlagSet.Parse(os.Args[2:])
-	case modules.PullClaimsCmd:
-		pullClaimsFlagSet.Parse(os.Args[2:])
-	case modules.CheckIbanCmd:
-		checkIbanFlagSet.Parse(os.Args[2:])
-	case modules.SignatureRequestCmd:
-		signatureRequestFlagSet.Parse(os.Args[2:])
-	default:
-		fmt.Printf("%s: '%s' is not a hodei-cli command.\n", os.Args[0], cmd)
-		usage()
-		os.Exit(1)
-	}
-
-	if customerSearchFlagSet.Parsed() {
-		if customerSearchOptions.Help {
-			customerSearchFlagSet.PrintDefaults()
-			os.Exit(0)
-		}
-		modules.CustomerSearch(&customerSearchOptions)
-	}
-
-	if pullCountriesFlagSet.Parsed() {
-		if pullCountriesOptions.Help {
-			pullCountriesFlagSet.PrintDefaults()
-			os.Exit(0)
-		}
-		modules.PullCountries(&pullCountriesOptions)
-	}
-
-	if pullProductsFlagSet.Parsed() {
-		if pullProductsOptions.Help {
-			pullProductsFlagSet.PrintDefaults()
-			os.Exit(0)
-		}
-		modules.PullProducts(&pullProductsOptions)
-	}
-
-	if pullAgreementsFlagSet.Parsed() {
-		if pullAgreementsOptions.Help {
-			pullAgreementsFlagSet.PrintDefaults()
-			os.Exit(0)
-		}
-		modules.PullAgreements(&pullAgreementsOptions)
-	}
-
-	if pullNetworksFlagSet.Parsed() {
-		if pullNetworksOptions.Help {
-			pullNetworksFlagSet.PrintDefaults()
-			os.Exit(0)
-		}
-		modules.PullNetworks(&pullNetworksOptions)
-	}
+	registry := modules.DefaultRegistry(client.Default)
+	// Load tests open their own connection with a channel per worker
+	registry.Register(modules.LoadModule{Publisher: client.Default, Registry: registry, DedicatedBroker: true})
+	registry.Register(versionModule{})
+	os.Exit(run(registry, os.Args[1:]))
+}
 
-	if pullCustomersFlagSet.Parsed() {
-		if pullCustomersOptions.Help {
-			pullCustomersFlagSet.PrintDefaults()
-			os.Exit(0)
-		}
-		modules.PullCustomers(&pullCustomersOptions)
+// run executes the command given in args and returns the exit status, which
+// for failed commands is the one documented for the kind of their error.
+func run(registry *modules.Registry, args []string) int {
+	profile, args := modules.ProfileArgs(args)
+	if len(args) < 1 {
+		usage(registry)
+		return 0
 	}
-
-	if pullProfessionsFlagSet.Parsed() {
-		if pullProfessionsOptions.Help {
-			pullProfessionsFlagSet.PrintDefaults()
-			os.Exit(0)
+	if profile != "" {
+		selected, err := modules.LoadProfile(modules.ConfigFile(), profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return 1
 		}
-		modules.PullProfessions(&pullProfessionsOptions)
+		modules.UseProfile(selected)
 	}
 
-	if pullPoliciesFlagSet.Parsed() {
-		if pullPoliciesOptions.Help {
-			pullPoliciesFlagSet.PrintDefaults()
-			os.Exit(0)
+	cmd, args := args[0], args[1:]
+	if cmd == helpCmd {
+		if len(args) == 0 {
+			usage(registry)
+			return 0
 		}
-		modules.PullPolicies(&pullPoliciesOptions)
+		cmd, args = args[0], []string{"-help"}
 	}
+	module, check := registry.Lookup(cmd)
 
-	if pullOrdersFlagSet.Parsed() {
-		if pullOrdersOptions.Help {
-			pullOrdersFlagSet.PrintDefaults()
-			os.Exit(0)
+	if !check {
+		fmt.Printf("%s: '%s' is not a hodei-cli command.\n", os.Args[0], cmd)
+		usage(registry)
+		return 1
+	}
+
+	// Long running commands stop on the first interrupt
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
 		}
-		res, err := modules.PullOrders(&pullOrdersOptions)
-		if err != nil {
-			log.Fatal(err)
+	}()
+
+	err := module.Execute(ctx, args)
+	client.Close()
+	cancel()
+	if err != nil {
+		if errors.Is(err, client.ErrReplyTimeout) {
+			fmt.Fprintln(os.Stderr, "No reply received before the timeout expired")
 		} else {
-			fmt.Println(res)
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		}
+		return modules.ExitCode(err)
 	}
+	return 0
+}
 
-	if pullCoveragesFlagSet.Parsed() {
-		if pullCoveragesOptions.Help {
-			pullCoveragesFlagSet.PrintDefaults()
-			os.Exit(0)
-		}
-		modules.PullCoverages(&pullCoveragesOptions)
-	}
+type versionModule struct {
+}
 
-	if pullClaimsFlagSet.Parsed() {
-		if pullClaimsOptions.Help {
-			pullClaimsFlagSet.PrintDefaults()
-			os.Exit(0)
-		}
-		modules.PullClaims(&pullClaimsOptions)
+func (m versionModule) Info() modules.ModuleInfo {
+	return modules.ModuleInfo{
+		Name:     versionCmd,
+		Category: modules.CategoryOther,
+		Short:    "Print the cli version",
 	}
+}
 
-	if checkIbanFlagSet.Parsed() {
-		if checkIbanOptions.Help {
-			checkIbanFlagSet.PrintDefaults()
-			os.Exit(0)
-		}
-		modules.CheckIban(&checkIbanOptions)
+func (m versionModule) Execute(ctx context.Context, args []string) error {
+	if len(args) > 0 && args[0] == "-help" {
+		fmt.Println(m.Info().Short)
+		return nil
 	}
+	fmt.Println("Hodei cli", version)
+	return nil
+}
 
-	if signatureRequestFlagSet.Parsed() {
-		if signatureRequestOptions.Help {
-			signatureRequestFlagSet.PrintDefaults()
-			os.Exit(0)
+func usage(registry *modules.Registry) {
+	fmt.Println()
+	fmt.Println("Usage: hodei-cli [--profile NAME] COMMAND [OPTIONS]")
+	for _, category := range modules.Categories {
+		names := registry.Category(category)
+		if len(names) == 0 {
+			continue
 		}
-		res, err := modules.SignatureRequest(&signatureRequestOptions)
-		if err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Println(res)
+		fmt.Println()
+		fmt.Println(category + ":")
+		for _, name := range names {
+			module, _ := registry.Lookup(name)
+			fmt.Printf("  %-22s %s\n", name, module.Info().Short)
 		}
 	}
-}
-
-func usage() {
-	fmt.Println(`
-Usage: hodei-cli COMMAND [OPTIONS]")
-
-Commands:
-  ` + modules.CustomerSearchCmd + `
-  ` + modules.PullCountriesCmd + `
-  ` + modules.PullProductsCmd + `
-  ` + modules.PullAgreementsCmd + `
-  ` + modules.PullNetworksCmd + `
-  ` + modules.PullCustomersCmd + `
-  ` + modules.PullProfessionsCmd + `
-  ` + modules.PullPoliciesCmd + `
-  ` + modules.PullOrdersCmd + `
-  ` + modules.PullCoveragesCmd + `
-  ` + modules.PullClaimsCmd + `
-  ` + modules.CheckIbanCmd + `
-  ` + modules.MongoResetCmd + `
-  ` + modules.SignatureRequestCmd + `
-  ` + versionCmd)
+	fmt.Println()
+	fmt.Println("Run 'hodei-cli help COMMAND' for more information on a command.")
 }
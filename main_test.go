@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/labcabrera/hodei-cli/modules"
+)
+
+type failingModule struct {
+	err error
+}
+
+func (m failingModule) Info() modules.ModuleInfo {
+	return modules.ModuleInfo{Name: "fail", Category: modules.CategoryOther, Short: "Fail"}
+}
+
+func (m failingModule) Execute(ctx context.Context, args []string) error {
+	return m.err
+}
+
+func TestRunExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{nil, 0},
+		{errors.New("boom"), 1},
+		{&client.Error{Kind: client.ErrConnectionRefused, Op: "dial"}, 3},
+		{fmt.Errorf("pull: %w", &client.Error{Kind: client.ErrConfiguration, Op: "parse uri"}), 8},
+		{&client.Error{Kind: client.ErrReplyTimeout, Op: "wait reply"}, 124},
+	}
+	for _, test := range tests {
+		registry := modules.NewRegistry()
+		registry.Register(failingModule{err: test.err})
+		if code := run(registry, []string{"fail"}); code != test.code {
+			t.Errorf("exit code %d for %v, want %d", code, test.err, test.code)
+		}
+	}
+	if code := run(modules.NewRegistry(), []string{"unknown"}); code != 1 {
+		t.Errorf("exit code %d for an unknown command", code)
+	}
+}
@@ -0,0 +1,232 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/streadway/amqp"
+)
+
+const BenchCmd = "bench"
+
+type BenchModule struct {
+	Publisher client.Publisher
+}
+
+type benchOptions struct {
+	exchange    string
+	routingKey  string
+	headers     headerFlags
+	body        string
+	file        string
+	requests    int
+	concurrency int
+	timeout     time.Duration
+	json        bool
+	verbose     bool
+	help        bool
+}
+
+// benchReport summarizes a benchmark. Latencies are in milliseconds and only
+// include requests answered by the service.
+type benchReport struct {
+	Requests    int     `json:"requests"`
+	Concurrency int     `json:"concurrency"`
+	DurationMs  float64 `json:"durationMs"`
+	Throughput  float64 `json:"throughput"`
+	Errors      int     `json:"errors"`
+	Timeouts    int     `json:"timeouts"`
+	MinMs       float64 `json:"minMs"`
+	MeanMs      float64 `json:"meanMs"`
+	P50Ms       float64 `json:"p50Ms"`
+	P90Ms       float64 `json:"p90Ms"`
+	P99Ms       float64 `json:"p99Ms"`
+	MaxMs       float64 `json:"maxMs"`
+}
+
+// payloadData is the data available to body and id templates.
+type payloadData struct {
+	Index int
+}
+
+var payloadFuncs = template.FuncMap{
+	"uuid": client.NewCorrelationId,
+	"randInt": func(min int, max int) int {
+		return min + rand.Intn(max-min+1)
+	},
+}
+
+func (m BenchModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     BenchCmd,
+		Category: CategoryTesting,
+		Short:    "Measure the response times of a service with concurrent requests",
+		Long: `Send request/reply messages from concurrent workers and report the throughput,
+errors, timeouts and latency percentiles. The body is a text/template with the
+request number {{.Index}} and the uuid and randInt functions.`,
+		Examples: []string{`hodei-cli bench -exchange cnp.sepa -key iban.validation -n 1000 -c 20 -body '{"iban":"ES{{randInt 10000 99999}}"}'`},
+	}
+}
+
+func (m BenchModule) Execute(ctx context.Context, args []string) error {
+	options := benchOptions{headers: headerFlags{}}
+	flagset := benchCreateFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	report, err := bench(ctx, m.Publisher, &options)
+	if report != nil {
+		printBenchReport(report, options.json)
+	}
+	return err
+}
+
+func benchCreateFlagSet(options *benchOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(BenchCmd, flag.ExitOnError)
+	messageFlags(fs, &options.exchange, &options.routingKey, options.headers, &options.body, &options.file)
+	fs.IntVar(&options.requests, "n", 100, "Number of requests")
+	fs.IntVar(&options.concurrency, "c", 10, "Number of concurrent requests")
+	fs.DurationVar(&options.timeout, "timeout", client.DefaultTimeout, "Reply timeout of each request")
+	fs.BoolVar(&options.json, "json", false, "Print the report as JSON")
+	fs.BoolVar(&options.verbose, "v", false, "Verbose")
+	fs.BoolVar(&options.help, "help", false, "Help")
+	brokerFlags(fs)
+	return fs
+}
+
+// bench sends options.requests requests from options.concurrency workers. The
+// body is a text/template rendered for each request with its index and the
+// uuid and randInt functions. When requests fail or time out the report is
+// returned with the error of the last one, which decides the exit code.
+func bench(ctx context.Context, publisher client.Publisher, options *benchOptions) (*benchReport, error) {
+	if options.exchange == "" && options.routingKey == "" {
+		return nil, fmt.Errorf("required exchange or routing key")
+	}
+	if options.requests <= 0 || options.concurrency <= 0 {
+		return nil, fmt.Errorf("requests and concurrency must be positive")
+	}
+	body, err := readBody(options.body, options.file)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(BenchCmd).Funcs(payloadFuncs).Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	latencies := make([]time.Duration, options.requests)
+	failures := make([]error, options.requests)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	started := time.Now()
+	for w := 0; w < options.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				var payload bytes.Buffer
+				if failures[i] = tmpl.Execute(&payload, payloadData{Index: i + 1}); failures[i] != nil {
+					continue
+				}
+				requestCtx, cancel := context.WithTimeout(ctx, options.timeout)
+				sent := time.Now()
+				_, failures[i] = publisher.SendAndReceive(requestCtx, options.exchange, options.routingKey, payload.String(), amqp.Table(options.headers), options.verbose)
+				latencies[i] = time.Since(sent)
+				cancel()
+				if failures[i] != nil && options.verbose {
+					log.Printf("Request %d failed: %s", i+1, failures[i])
+				}
+			}
+		}()
+	}
+	// Interrupted benchmarks report the requests sent so far
+	sent := 0
+	for ; sent < options.requests && ctx.Err() == nil; sent++ {
+		indexes <- sent
+	}
+	close(indexes)
+	wg.Wait()
+	elapsed := time.Since(started)
+
+	report := &benchReport{
+		Requests:    sent,
+		Concurrency: options.concurrency,
+		DurationMs:  milliseconds(elapsed),
+		Throughput:  float64(sent) / elapsed.Seconds(),
+	}
+	var answered []time.Duration
+	var last error
+	for i, err := range failures[:sent] {
+		switch {
+		case err == nil:
+			answered = append(answered, latencies[i])
+		case errors.Is(err, client.ErrReplyTimeout):
+			report.Timeouts++
+			last = err
+		default:
+			report.Errors++
+			last = err
+		}
+	}
+	if len(answered) > 0 {
+		sort.Slice(answered, func(i, j int) bool { return answered[i] < answered[j] })
+		var total time.Duration
+		for _, latency := range answered {
+			total += latency
+		}
+		report.MinMs = milliseconds(answered[0])
+		report.MeanMs = milliseconds(total / time.Duration(len(answered)))
+		report.P50Ms = milliseconds(percentile(answered, 50))
+		report.P90Ms = milliseconds(percentile(answered, 90))
+		report.P99Ms = milliseconds(percentile(answered, 99))
+		report.MaxMs = milliseconds(answered[len(answered)-1])
+	}
+	if last != nil {
+		return report, fmt.Errorf("%d of %d requests failed: %w", report.Errors+report.Timeouts, sent, last)
+	}
+	return report, nil
+}
+
+// percentile returns the nearest-rank percentile p of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
+
+func printBenchReport(report *benchReport, asJson bool) {
+	if asJson {
+		b, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(b))
+		return
+	}
+	fmt.Printf("Requests:    %d\n", report.Requests)
+	fmt.Printf("Concurrency: %d\n", report.Concurrency)
+	fmt.Printf("Duration:    %.2f ms\n", report.DurationMs)
+	fmt.Printf("Throughput:  %.2f req/s\n", report.Throughput)
+	fmt.Printf("Errors:      %d\n", report.Errors)
+	fmt.Printf("Timeouts:    %d\n", report.Timeouts)
+	fmt.Printf("Latency:     min %.2f ms, mean %.2f ms, p50 %.2f ms, p90 %.2f ms, p99 %.2f ms, max %.2f ms\n",
+		report.MinMs, report.MeanMs, report.P50Ms, report.P90Ms, report.P99Ms, report.MaxMs)
+}
@@ -0,0 +1,59 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestBench(t *testing.T) {
+	broker := client.NewFakeBroker()
+	broker.Reply("cnp.sepa", "iban.validation", `{"valid":true}`)
+	options := benchOptions{exchange: "cnp.sepa", routingKey: "iban.validation", headers: headerFlags{},
+		body: `{"iban":"ES{{.Index}}"}`, requests: 20, concurrency: 4, timeout: client.DefaultTimeout}
+	report, err := bench(context.Background(), broker, &options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Requests != 20 || report.Errors != 0 || report.Timeouts != 0 || len(broker.Messages) != 20 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	bodies := make(map[string]bool)
+	for _, message := range broker.Messages {
+		bodies[message.Body] = true
+	}
+	if !bodies[`{"iban":"ES1"}`] || !bodies[`{"iban":"ES20"}`] {
+		t.Errorf("unexpected bodies %v", bodies)
+	}
+
+	options.routingKey = "customer.search"
+	report, err = bench(context.Background(), broker, &options)
+	if report == nil || report.Timeouts != 20 {
+		t.Errorf("expected timeouts, got %+v", report)
+	}
+	if !errors.Is(err, client.ErrReplyTimeout) || ExitCode(err) != exitTimeout {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	broker.Fail("cnp.sepa", "iban.validation", &client.Error{Kind: client.ErrChannel, Op: "publish"})
+	options.routingKey = "iban.validation"
+	if report, err = bench(context.Background(), broker, &options); report == nil || report.Errors != 20 || ExitCode(err) != exitChannel {
+		t.Errorf("unexpected report %+v, %v", report, err)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	if p := percentile(latencies, 50); p != 50*time.Millisecond {
+		t.Errorf("unexpected p50 %s", p)
+	}
+	if p := percentile(latencies, 99); p != 99*time.Millisecond {
+		t.Errorf("unexpected p99 %s", p)
+	}
+}
@@ -0,0 +1,44 @@
+package modules
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+// brokerFlags registers the connection options shared by every command that
+// talks to RabbitMQ. Values default to the APP_AMQP_* and APP_TRACE_*
+// environment variables.
+func brokerFlags(fs *flag.FlagSet) {
+	config := client.DefaultConfig()
+	fs.IntVar(&config.Retry.MaxAttempts, "retries", config.Retry.MaxAttempts, "Max attempts on transient broker failures")
+	fs.DurationVar(&config.Retry.Backoff, "retry-backoff", config.Retry.Backoff, "Initial backoff between attempts")
+	fs.BoolVar(&config.DirectReplyTo, "direct-reply", config.DirectReplyTo, "Receive replies using amq.rabbitmq.reply-to")
+	fs.StringVar(&config.Record, "record", config.Record, "Append sent messages and replies to a JSON lines file")
+	fs.IntVar(&config.Message.Priority, "priority", config.Message.Priority, "Message priority from 1 to 255 (0 for no priority)")
+	fs.DurationVar(&config.Message.Expiration, "expiration", config.Message.Expiration, "Message TTL (0 for no expiration)")
+	fs.IntVar(&config.Message.DeliveryMode, "delivery-mode", config.Message.DeliveryMode, "Delivery mode: 1 transient, 2 persistent (0 for command default)")
+	fs.StringVar(&config.Message.ContentType, "content-type", config.Message.ContentType, "Message content type")
+	fs.StringVar(&config.Message.MessageId, "message-id", config.Message.MessageId, "Message id, auto to generate one per message")
+	fs.StringVar(&config.Message.AppId, "app-id", config.Message.AppId, "Message app id")
+	fs.StringVar(&config.Trace.Parent, "trace-parent", config.Trace.Parent, "Parent traceparent header or trace id")
+	fs.StringVar(&config.Trace.State, "trace-state", config.Trace.State, "W3C tracestate header")
+	fs.BoolVar(&config.Trace.B3, "b3", config.Trace.B3, "Also send B3 trace headers")
+	fs.StringVar(&config.Signing.KeyId, "sign-key-id", config.Signing.KeyId, "Id of the signing key used to sign messages")
+	fs.Var((*signedHeaders)(&config.Signing.Headers), "sign-headers", "Comma separated headers included in the message signature")
+}
+
+type signedHeaders []string
+
+func (h *signedHeaders) String() string {
+	if h == nil {
+		return ""
+	}
+	return strings.Join(*h, ",")
+}
+
+func (h *signedHeaders) Set(value string) error {
+	*h = client.SplitHeaders(value)
+	return nil
+}
@@ -1,8 +1,10 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"log"
+	"time"
 
 	"github.com/labcabrera/hodei-cli/client"
 	"github.com/streadway/amqp"
@@ -13,11 +15,38 @@ const CheckIbanCmd = "check-iban"
 type CheckIbanOptions struct {
 	CountryCode string
 	Iban        string
+	Timeout     time.Duration
+	Collect     client.CollectOptions
 	Help        bool
 	Verbose     bool
 }
 
-func CheckIban(options *CheckIbanOptions) (res string, err error) {
+type CheckIbanModule struct {
+	Publisher client.Publisher
+}
+
+func (m CheckIbanModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     CheckIbanCmd,
+		Category: CategoryServices,
+		Short:    "Validate an IBAN",
+		Examples: []string{"hodei-cli check-iban -country ESP -iban ES9121000418450200051332"},
+	}
+}
+
+func (m CheckIbanModule) Execute(ctx context.Context, args []string) error {
+	options := CheckIbanOptions{}
+	flagset := CheckIbanFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return printReply(CheckIban(ctx, m.Publisher, &options))
+}
+
+func CheckIban(ctx context.Context, publisher client.Publisher, options *CheckIbanOptions) (res string, err error) {
 	if options.Verbose {
 		log.Printf("Validating IBAN %s", options.Iban)
 	}
@@ -25,7 +54,9 @@ func CheckIban(options *CheckIbanOptions) (res string, err error) {
 		"App-Source": "hodei-cli",
 	}
 	body := `{"countryCode": "` + options.CountryCode + `","iban": "` + options.Iban + `"}`
-	res, err = client.SendAndReceive("cnp.sepa", "iban.validation", body, headers, options.Verbose)
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+	res, err = sendRequest(ctx, publisher, "cnp.sepa", "iban.validation", body, headers, options.Collect, options.Verbose)
 	return
 }
 
@@ -33,7 +64,10 @@ func CheckIbanFlagSet(options *CheckIbanOptions) *flag.FlagSet {
 	fs := flag.NewFlagSet(CheckIbanCmd, flag.ExitOnError)
 	fs.StringVar(&options.Iban, "iban", "", "IBAN")
 	fs.StringVar(&options.CountryCode, "country", "", "Country ISO3 code")
+	fs.DurationVar(&options.Timeout, "timeout", client.DefaultTimeout, "Reply timeout")
+	collectFlags(fs, &options.Collect)
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
 	return fs
 }
@@ -0,0 +1,240 @@
+package modules
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/streadway/amqp"
+)
+
+const DlqCmd = "dlq"
+
+const (
+	dlqListCmd    = "list"
+	dlqRequeueCmd = "requeue"
+	dlqPurgeCmd   = "purge"
+)
+
+type DlqModule struct {
+	Browser client.Browser
+}
+
+type dlqOptions struct {
+	queue     string
+	limit     int
+	selection string
+	all       bool
+	whole     bool
+	verbose   bool
+	help      bool
+}
+
+func (m DlqModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     DlqCmd,
+		Category: CategoryBroker,
+		Short:    "Browse, requeue or purge dead-lettered messages",
+		Long: `Browse, requeue or purge the messages of a dead-letter queue.
+
+Commands:
+  ` + dlqListCmd + `     List dead-lettered messages without removing them
+  ` + dlqRequeueCmd + `  Republish messages to their original exchange and routing key
+  ` + dlqPurgeCmd + `    Remove messages from the queue`,
+		Examples: []string{
+			"hodei-cli dlq list -queue cnp.referential.customer.pull.dlq",
+			"hodei-cli dlq requeue -queue cnp.referential.customer.pull.dlq -n 1,3",
+			"hodei-cli dlq purge -queue cnp.referential.customer.pull.dlq -whole-queue",
+		},
+	}
+}
+
+func (m DlqModule) Execute(ctx context.Context, args []string) error {
+	if len(args) == 0 || isHelp(args[0]) {
+		fmt.Println(m.Info().Long)
+		fmt.Printf("\nRun 'hodei-cli %s COMMAND -help' for the options of a command.\n", DlqCmd)
+		if len(args) == 0 {
+			return fmt.Errorf("missing %s command", DlqCmd)
+		}
+		return nil
+	}
+	cmd := args[0]
+	switch cmd {
+	case dlqListCmd, dlqRequeueCmd, dlqPurgeCmd:
+	default:
+		return fmt.Errorf("unknown %s command '%s'", DlqCmd, cmd)
+	}
+	options := dlqOptions{}
+	flagset := dlqCreateFlagSet(cmd, &options)
+	flagset.Parse(args[1:])
+
+	if options.help {
+		printHelp(ModuleInfo{Name: DlqCmd + " " + cmd, Short: m.Info().Short}, flagset)
+		return nil
+	} else if options.queue == "" {
+		return fmt.Errorf("required queue parameter")
+	}
+	switch cmd {
+	case dlqRequeueCmd:
+		return dlqRequeue(ctx, m.Browser, &options)
+	case dlqPurgeCmd:
+		return dlqPurge(ctx, m.Browser, &options)
+	}
+	return dlqList(m.Browser, &options)
+}
+
+func dlqCreateFlagSet(cmd string, options *dlqOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(DlqCmd+" "+cmd, flag.ExitOnError)
+	fs.StringVar(&options.queue, "queue", "", "Dead-letter queue")
+	fs.IntVar(&options.limit, "limit", 100, "Max number of messages to read (0 for all)")
+	if cmd != dlqListCmd {
+		fs.StringVar(&options.selection, "n", "", "Comma separated message numbers as shown by list")
+		fs.BoolVar(&options.all, "all", false, "All messages read, up to -limit")
+	}
+	if cmd == dlqPurgeCmd {
+		fs.BoolVar(&options.whole, "whole-queue", false, "Purge the whole queue, including the messages beyond -limit")
+	}
+	fs.BoolVar(&options.verbose, "v", false, "Verbose")
+	fs.BoolVar(&options.help, "help", false, "Help")
+	brokerFlags(fs)
+	return fs
+}
+
+func dlqList(browser client.Browser, options *dlqOptions) error {
+	queue, err := browser.Browse(options.queue, options.limit)
+	if err != nil {
+		return fmt.Errorf("reading queue: %w", err)
+	}
+	defer queue.Close()
+
+	messages := queue.Messages()
+	for i := range messages {
+		printDeadLetter(i+1, &messages[i])
+	}
+	fmt.Printf("%d messages in %s\n", len(messages), options.queue)
+	return nil
+}
+
+func dlqRequeue(ctx context.Context, browser client.Browser, options *dlqOptions) error {
+	return dlqApply(ctx, browser, options, "Requeued", func(queue client.QueueBrowser, i int) error {
+		return queue.Republish(ctx, i)
+	})
+}
+
+func dlqPurge(ctx context.Context, browser client.Browser, options *dlqOptions) error {
+	if options.whole {
+		count, err := browser.Purge(options.queue)
+		if err != nil {
+			return fmt.Errorf("purging queue: %w", err)
+		}
+		fmt.Printf("Purged %d messages from %s\n", count, options.queue)
+		return nil
+	}
+	return dlqApply(ctx, browser, options, "Purged", client.QueueBrowser.Ack)
+}
+
+func dlqApply(ctx context.Context, browser client.Browser, options *dlqOptions, action string, apply func(client.QueueBrowser, int) error) error {
+	selected, err := parseSelection(options.selection)
+	if err != nil {
+		return fmt.Errorf("invalid selection: %w", err)
+	} else if len(selected) == 0 && !options.all {
+		return fmt.Errorf("required -n or -all parameter")
+	}
+	queue, err := browser.Browse(options.queue, options.limit)
+	if err != nil {
+		return fmt.Errorf("reading queue: %w", err)
+	}
+	defer queue.Close()
+
+	messages := queue.Messages()
+	if options.all {
+		selected = make([]int, len(messages))
+		for i := range selected {
+			selected[i] = i + 1
+		}
+	}
+	count := 0
+	var failed []int
+	var last error
+	for _, n := range selected {
+		// Messages not processed when interrupted go back to the queue
+		if ctx.Err() != nil {
+			break
+		}
+		if n < 1 || n > len(messages) {
+			log.Printf("Message %d not found", n)
+			failed = append(failed, n)
+			last = fmt.Errorf("message %d not found", n)
+			continue
+		}
+		if err := apply(queue, n-1); err != nil {
+			log.Printf("Message %d: %s", n, err)
+			failed = append(failed, n)
+			last = err
+			continue
+		}
+		if options.verbose {
+			log.Printf("%s message %d", action, n)
+		}
+		count++
+	}
+	fmt.Printf("%s %d messages from %s\n", action, count, options.queue)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		// The last error keeps the exit code of its kind
+		return fmt.Errorf("messages %v failed: %w", failed, last)
+	}
+	return nil
+}
+
+// parseSelection reads a comma separated list of message numbers, ignoring
+// repeated ones, whose delivery tag can only be acknowledged once.
+func parseSelection(selection string) ([]int, error) {
+	var selected []int
+	seen := make(map[int]bool)
+	for _, value := range strings.Split(selection, ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[n] {
+			seen[n] = true
+			selected = append(selected, n)
+		}
+	}
+	return selected, nil
+}
+
+func printDeadLetter(n int, d *amqp.Delivery) {
+	fmt.Printf("--- #%d %s %s\n", n, d.Exchange, d.RoutingKey)
+	printProperty("Message-Id", d.MessageId)
+	printProperty("Correlation-Id", d.CorrelationId)
+	if !d.Timestamp.IsZero() {
+		printProperty("Timestamp", d.Timestamp.Format(tailTimeFormat))
+	}
+	for _, death := range client.Deaths(d.Headers) {
+		fmt.Printf("x-death: %s from queue %s (exchange %s, keys %s) x%d at %s\n",
+			death.Reason, death.Queue, death.Exchange, strings.Join(death.RoutingKeys, ","), death.Count, death.Time.Format(tailTimeFormat))
+	}
+	keys := make([]string, 0, len(d.Headers))
+	for key := range d.Headers {
+		if key != "x-death" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		printProperty(key, fmt.Sprint(d.Headers[key]))
+	}
+	fmt.Println(strings.TrimSpace(string(d.Body)))
+}
@@ -0,0 +1,83 @@
+package modules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/streadway/amqp"
+)
+
+func TestParseSelection(t *testing.T) {
+	selected, err := parseSelection("1, 3,,5,1")
+	if err != nil || len(selected) != 3 || selected[0] != 1 || selected[1] != 3 || selected[2] != 5 {
+		t.Errorf("unexpected selection %v, %v", selected, err)
+	}
+	if _, err := parseSelection("1,x"); err == nil {
+		t.Error("expected error for invalid number")
+	}
+}
+
+func deadLetter(id string, routingKey string) amqp.Delivery {
+	death := amqp.Table{"queue": "customer.pull", "reason": "rejected", "exchange": "cnp.referential", "routing-keys": []interface{}{routingKey}}
+	return amqp.Delivery{
+		MessageId: id,
+		UserId:    "cnp-customers",
+		Headers:   amqp.Table{"x-death": []interface{}{death}},
+		Body:      []byte(id),
+	}
+}
+
+func TestDlq(t *testing.T) {
+	const queue = "customer.pull.dlq"
+	broker := client.NewFakeBroker()
+	broker.Enqueue(queue, deadLetter("1", "customer.pull"), deadLetter("2", "customer.pull"), deadLetter("3", "customer.pull"))
+	dlq := DlqModule{Browser: broker}
+	ctx := context.Background()
+
+	if err := dlq.Execute(ctx, []string{"foo"}); err == nil || err.Error() != "unknown dlq command 'foo'" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := dlq.Execute(ctx, []string{"list"}); err == nil || err.Error() != "required queue parameter" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := dlq.Execute(ctx, []string{"list", "-queue", queue}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dlq.Execute(ctx, []string{"requeue", "-queue", queue}); err == nil {
+		t.Error("requeue without selection")
+	}
+
+	if err := dlq.Execute(ctx, []string{"requeue", "-queue", queue, "-n", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := broker.Last(); msg == nil || msg.Exchange != "cnp.referential" || msg.RoutingKey != "customer.pull" || msg.Body != "2" {
+		t.Errorf("unexpected requeued message %+v", msg)
+	}
+	if left := broker.Queue(queue); len(left) != 2 || left[0].MessageId != "1" || left[1].MessageId != "3" {
+		t.Errorf("unexpected queue %v", left)
+	}
+
+	// Out of range messages fail without touching the others
+	if err := dlq.Execute(ctx, []string{"purge", "-queue", queue, "-n", "1,5"}); err == nil {
+		t.Error("expected error for a missing message")
+	}
+	if left := broker.Queue(queue); len(left) != 1 || left[0].MessageId != "3" {
+		t.Errorf("unexpected queue %v", left)
+	}
+
+	// -all only takes the messages read, -whole-queue empties the queue
+	broker.Enqueue(queue, deadLetter("4", "customer.pull"), deadLetter("5", "customer.pull"))
+	if err := dlq.Execute(ctx, []string{"purge", "-queue", queue, "-all", "-limit", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if left := broker.Queue(queue); len(left) != 1 || left[0].MessageId != "5" {
+		t.Errorf("unexpected queue %v", left)
+	}
+	if err := dlq.Execute(ctx, []string{"purge", "-queue", queue, "-whole-queue"}); err != nil {
+		t.Fatal(err)
+	}
+	if left := broker.Queue(queue); len(left) != 0 {
+		t.Errorf("unexpected queue %v", left)
+	}
+}
@@ -0,0 +1,42 @@
+package modules
+
+import "sort"
+
+// Endpoint is an exchange and routing key a command sends messages to.
+type Endpoint struct {
+	Command    string
+	Exchange   string
+	RoutingKey string
+}
+
+// Endpoints lists every destination used by the registered commands. It is
+// the reference for topology checks, so new commands must be added here.
+var Endpoints = []Endpoint{
+	{PullCountriesCmd, "cnp.referential", "country.pull"},
+	{PullProductsCmd, "cnp.referential", "product.pull"},
+	{PullAgreementsCmd, "cnp.referential", "agreement.pull"},
+	{PullNetworksCmd, "cnp.referential", "network.pull"},
+	{PullCustomersCmd, "cnp.referential", "customer.pull"},
+	{PullProfessionsCmd, "cnp.referential", "profession.pull"},
+	{PullPoliciesCmd, "ppi.referential", "policy.pull"},
+	{PullOrdersCmd, "cnp.referential", "order.pull"},
+	{PullCoveragesCmd, "cnp.referential", "coverage.pull"},
+	{PullClaimsCmd, "cnp.referential", "claim.pull"},
+	{CustomerSearchCmd, "cnp.customer", "customer.search"},
+	{CheckIbanCmd, "cnp.sepa", "iban.validation"},
+	{SignatureRequestCmd, "cnp.esignature", "signature.request"},
+}
+
+// endpointExchanges returns the exchanges of Endpoints sorted by name.
+func endpointExchanges() []string {
+	var exchanges []string
+	seen := make(map[string]bool)
+	for _, endpoint := range Endpoints {
+		if !seen[endpoint.Exchange] {
+			seen[endpoint.Exchange] = true
+			exchanges = append(exchanges, endpoint.Exchange)
+		}
+	}
+	sort.Strings(exchanges)
+	return exchanges
+}
@@ -0,0 +1,120 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestPullCommands(t *testing.T) {
+	tests := []struct {
+		name       string
+		send       func(client.Publisher) error
+		exchange   string
+		routingKey string
+		body       string
+	}{
+		{"countries", func(p client.Publisher) error {
+			return PullCountries(context.Background(), p, &PullCountriesOptions{})
+		}, "cnp.referential", "country.pull", ``},
+		{"professions", func(p client.Publisher) error {
+			return PullProfessions(context.Background(), p, &PullProfessionsOptions{})
+		}, "cnp.referential", "profession.pull", `{}`},
+		{"products", func(p client.Publisher) error {
+			return PullProducts(context.Background(), p, &PullProductsOptions{Id: "1", ExternalCode: "P1", Username: "u", Authorities: "a"})
+		}, "cnp.referential", "product.pull", `{"id": "1","externalCode": "P1"}`},
+		{"agreements", func(p client.Publisher) error {
+			return PullAgreements(context.Background(), p, &PullAgreementsOptions{Id: "1", ExternalCode: "A1", Username: "u", Authorities: "a"})
+		}, "cnp.referential", "agreement.pull", `{"id": "1","externalCode": "A1"}`},
+		{"networks", func(p client.Publisher) error {
+			return PullNetworks(context.Background(), p, &PullNetworksOptions{IdCard: "70111222A", Username: "u", Authorities: "a"})
+		}, "cnp.referential", "network.pull", `{"id": "","externalCode": "","idCard": "70111222A"}`},
+		{"customers", func(p client.Publisher) error {
+			return PullCustomers(context.Background(), p, &PullCustomerOptions{Id: "1", Username: "u", Authorities: "a"})
+		}, "cnp.referential", "customer.pull", `{"id": "1","externalCode": "","idCard": ""}`},
+		{"policies", func(p client.Publisher) error {
+			return PullPolicies(context.Background(), p, &PullPoliciesOptions{Product: "ppi", AgreementId: "20725", Username: "u", Authorities: "a"})
+		}, "ppi.referential", "policy.pull", `{"id": "", "externalCode": "", "agreementId":"20725"}`},
+		{"orders", func(p client.Publisher) error {
+			return PullOrders(context.Background(), p, &PullOrdersOptions{Id: "1", PolicyId: "2", Username: "u", Authorities: "a"})
+		}, "cnp.referential", "order.pull", `{"id": "1","externalCode": "","policyId":"2","policyExternalCode":""}`},
+		{"coverages", func(p client.Publisher) error {
+			return PullCoverages(context.Background(), p, &PullCoveragesOptions{Id: "1", Username: "u", Authorities: "a"})
+		}, "cnp.referential", "coverage.pull", `{"id":"1","externalCode":"","policyId":"","policyExternalCode":""}`},
+		{"claims", func(p client.Publisher) error {
+			return PullClaims(context.Background(), p, &PullClaimsOptions{ExternalCode: "C1", Username: "u", Authorities: "a"})
+		}, "cnp.referential", "claim.pull", `{"id":"","externalCode":"C1","policyId":"","policyExternalCode":""}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			broker := client.NewFakeBroker()
+			if err := test.send(broker); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			msg := broker.Last()
+			if msg == nil {
+				t.Fatal("no message published")
+			}
+			if msg.Request {
+				t.Error("pull sent as a request")
+			}
+			if msg.Exchange != test.exchange || msg.RoutingKey != test.routingKey {
+				t.Errorf("published to %s/%s, want %s/%s", msg.Exchange, msg.RoutingKey, test.exchange, test.routingKey)
+			}
+			if msg.Body != test.body {
+				t.Errorf("body %s, want %s", msg.Body, test.body)
+			}
+			if !registeredEndpoint(msg) {
+				t.Errorf("%s/%s missing from Endpoints", msg.Exchange, msg.RoutingKey)
+			}
+		})
+	}
+}
+
+func registeredEndpoint(msg *client.Message) bool {
+	for _, endpoint := range Endpoints {
+		if endpoint.Exchange == msg.Exchange && endpoint.RoutingKey == msg.RoutingKey {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPullUsesAuthorizationHeaders(t *testing.T) {
+	broker := client.NewFakeBroker()
+	PullProducts(context.Background(), broker, &PullProductsOptions{Id: "1", Username: "demo", Authorities: "admin"})
+	headers := broker.Last().Headers
+	if headers["App-Username"] != "demo" || headers["App-Authorities"] != "admin" {
+		t.Errorf("unexpected headers %v", headers)
+	}
+}
+
+func TestPullReportsBrokerErrors(t *testing.T) {
+	broker := client.NewFakeBroker()
+	broker.Fail("cnp.referential", "country.pull", &client.UnroutableError{Exchange: "cnp.referential", RoutingKey: "country.pull"})
+	err := PullCountries(context.Background(), broker, &PullCountriesOptions{})
+	if !errors.Is(err, client.ErrUnroutable) {
+		t.Errorf("expected unroutable error, got %v", err)
+	}
+}
+
+func TestEndpointsTopology(t *testing.T) {
+	topology := endpointsTopology()
+	if len(topology.Queues) != 2*len(Endpoints) || len(topology.Bindings) != 2*len(Endpoints) {
+		t.Errorf("unexpected topology size: %d queues, %d bindings", len(topology.Queues), len(topology.Bindings))
+	}
+	exchanges := make(map[string]bool)
+	for _, exchange := range topology.Exchanges {
+		if exchanges[exchange.Name] {
+			t.Errorf("exchange %s declared twice", exchange.Name)
+		}
+		exchanges[exchange.Name] = true
+	}
+	for _, binding := range topology.Bindings {
+		if !exchanges[binding.Exchange] {
+			t.Errorf("binding to undeclared exchange %s", binding.Exchange)
+		}
+	}
+}
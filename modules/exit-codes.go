@@ -0,0 +1,62 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+// Exit codes returned by the cli, documented in the README
+const (
+	exitError             = 1
+	exitConnectionRefused = 3
+	exitAuthentication    = 4
+	exitChannel           = 5
+	exitUnroutable        = 6
+	exitNacked            = 7
+	exitConfiguration     = 8
+	exitNotFound          = 9
+	exitManagement        = 10
+	exitTimeout           = 124
+)
+
+// ExitCode returns the exit code documented for the kind of err.
+func ExitCode(err error) int {
+	switch {
+	case errors.Is(err, client.ErrConnectionRefused):
+		return exitConnectionRefused
+	case errors.Is(err, client.ErrAuthentication):
+		return exitAuthentication
+	case errors.Is(err, client.ErrChannel):
+		return exitChannel
+	case errors.Is(err, client.ErrUnroutable):
+		return exitUnroutable
+	case errors.Is(err, client.ErrNacked):
+		return exitNacked
+	case errors.Is(err, client.ErrConfiguration):
+		return exitConfiguration
+	case errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrManagement):
+		return exitManagement
+	case errors.Is(err, client.ErrReplyTimeout):
+		return exitTimeout
+	}
+	return exitError
+}
+
+func printReply(res string, err error) error {
+	// Replies collected before a timeout are printed anyway
+	if res != "" {
+		fmt.Println(res)
+	}
+	return err
+}
+
+func reportPublish(err error) error {
+	if err == nil {
+		fmt.Println("Message accepted and routed by the broker")
+	}
+	return err
+}
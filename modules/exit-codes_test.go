@@ -0,0 +1,25 @@
+package modules
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{fmt.Errorf("required arguments"), exitError},
+		{&client.Error{Kind: client.ErrReplyTimeout, Op: "request"}, exitTimeout},
+		{fmt.Errorf("publish: %w", &client.Error{Kind: client.ErrUnroutable, Op: "publish"}), exitUnroutable},
+		{fmt.Errorf("reading bindings: %w", &client.Error{Kind: client.ErrNotFound, Op: "management api"}), exitNotFound},
+	}
+	for _, test := range tests {
+		if code := ExitCode(test.err); code != test.code {
+			t.Errorf("ExitCode(%v) = %d, want %d", test.err, code, test.code)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package modules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/streadway/amqp"
+)
+
+// headerFlags collects repeated -H key=value arguments.
+type headerFlags amqp.Table
+
+func (h headerFlags) String() string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = fmt.Sprintf("%s=%v", key, h[key])
+	}
+	return strings.Join(values, ",")
+}
+
+func (h headerFlags) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected key=value, got '%s'", value)
+	}
+	h[parts[0]] = parts[1]
+	return nil
+}
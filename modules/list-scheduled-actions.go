@@ -4,8 +4,6 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
-	"os"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -27,18 +25,26 @@ type listScheduledActionsOptions struct {
 	help       bool
 }
 
-func (m ListScheduledActionsModule) Execute(args []string) {
+func (m ListScheduledActionsModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     ListScheduledActionsCmd,
+		Category: CategoryMongo,
+		Short:    "List the scheduled actions",
+		Long:     "List the last 25 scheduled actions stored in the cnp-actions database.",
+		Examples: []string{"hodei-cli scheduled-actions"},
+	}
+}
+
+func (m ListScheduledActionsModule) Execute(ctx context.Context, args []string) error {
 	executionOptions := listScheduledActionsOptions{}
 	flagset := listScheduledActionsCreateFlagSet(&executionOptions)
 	flagset.Parse(args)
 
-	if flagset.Parsed() {
-		if executionOptions.help {
-			flagset.PrintDefaults()
-		} else {
-			listScheduledActions(&executionOptions)
-		}
+	if executionOptions.help {
+		printHelp(m.Info(), flagset)
+		return nil
 	}
+	return listScheduledActions(ctx, &executionOptions)
 }
 
 func listScheduledActionsCreateFlagSet(executionOptions *listScheduledActionsOptions) *flag.FlagSet {
@@ -50,19 +56,23 @@ func listScheduledActionsCreateFlagSet(executionOptions *listScheduledActionsOpt
 	return fs
 }
 
-func listScheduledActions(executionOptions *listScheduledActionsOptions) {
-	clientOptions := options.Client().ApplyURI("mongodb://localhost:27017")
-	client, err := mongo.Connect(context.TODO(), clientOptions)
+func listScheduledActions(ctx context.Context, executionOptions *listScheduledActionsOptions) error {
+	mongoUri := mongoUriValue()
+	if mongoUri == "" {
+		mongoUri = defaultMongoUri
+	}
+	clientOptions := options.Client().ApplyURI(mongoUri)
+	client, err := mongo.Connect(ctx, clientOptions)
 
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Check the connection
-	err = client.Ping(context.TODO(), nil)
+	err = client.Ping(ctx, nil)
 
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	if executionOptions.verbose {
@@ -84,22 +94,20 @@ func listScheduledActions(executionOptions *listScheduledActionsOptions) {
 	findOptions.SetLimit(25)
 
 	var results []*model.ScheduledAction
-	cur, err := collection.Find(context.TODO(), filter, findOptions)
+	cur, err := collection.Find(ctx, filter, findOptions)
 	if err != nil {
-		log.Fatal(err)
-		os.Exit(1)
+		return err
 	}
 
-	for cur.Next(context.TODO()) {
+	for cur.Next(ctx) {
 		var elem model.ScheduledAction
 		err := cur.Decode(&elem)
 		if err != nil {
-			log.Fatal(err)
-			os.Exit(1)
+			return err
 		}
 		results = append(results, &elem)
 	}
-	cur.Close(context.TODO())
+	cur.Close(ctx)
 
 	fmt.Printf(printTemplate, "Id", "EntityId", "EntityType", "ActionType", "Execution", "Code")
 	for _, action := range results {
@@ -109,5 +117,5 @@ func listScheduledActions(executionOptions *listScheduledActionsOptions) {
 		}
 		fmt.Printf(printTemplate, action.Id.Hex(), action.EntityId, action.EntityType, action.ActionType, executed, action.Result.Code)
 	}
-
+	return nil
 }
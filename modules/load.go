@@ -0,0 +1,313 @@
+package modules
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+const LoadCmd = "load"
+
+// loadTick is how often the scheduler publishes the messages due.
+const loadTick = 10 * time.Millisecond
+
+type LoadModule struct {
+	Publisher client.Publisher
+	// Registry holds the pull commands whose messages can be sent
+	Registry *Registry
+	// DedicatedBroker publishes through a new connection of the default
+	// broker configuration with a channel per worker instead of Publisher
+	DedicatedBroker bool
+}
+
+type loadOptions struct {
+	command     string
+	field       string
+	idsFile     string
+	generator   string
+	rate        float64
+	duration    time.Duration
+	rampUp      time.Duration
+	concurrency int
+	username    string
+	authorities string
+	verbose     bool
+	help        bool
+}
+
+type loadReport struct {
+	Sent    int64
+	Errors  int64
+	Elapsed time.Duration
+}
+
+func (m LoadModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     LoadCmd,
+		Category: CategoryTesting,
+		Short:    "Publish pull messages at a target rate",
+		Long: `Publish the messages of a pull command at a target rate for a duration, with an
+optional linear ramp-up. Ids are read in a loop from a file or generated with a
+text/template and set as the -field flag of the command, and a stats line is
+shown every second.`,
+		Examples: []string{"hodei-cli load -cmd pull-customers -field idcard -ids idcards.txt -rate 200 -duration 5m -ramp-up 1m -u demo -a demo"},
+	}
+}
+
+func (m LoadModule) Execute(ctx context.Context, args []string) error {
+	options := loadOptions{}
+	flagset := loadCreateFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	publisher := m.Publisher
+	if m.DedicatedBroker {
+		config := *client.DefaultConfig()
+		config.PoolSize = options.concurrency
+		broker := client.NewBroker(config)
+		defer broker.Close()
+		publisher = broker
+	}
+
+	report, err := load(ctx, publisher, m.Registry, &options, os.Stderr)
+	if report != nil {
+		fmt.Printf("Sent %d messages in %s (%.1f msg/s), %d errors\n", report.Sent, report.Elapsed.Round(time.Millisecond),
+			float64(report.Sent)/report.Elapsed.Seconds(), report.Errors)
+	}
+	return err
+}
+
+func loadCreateFlagSet(options *loadOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(LoadCmd, flag.ExitOnError)
+	fs.StringVar(&options.command, "cmd", PullCustomersCmd, "Pull command whose messages are sent")
+	fs.StringVar(&options.field, "field", "id", "Flag of the pull command set to each id, empty to send no ids")
+	fs.StringVar(&options.idsFile, "ids", "", "File with one id per line, used in a loop")
+	fs.StringVar(&options.generator, "generator", "{{uuid}}", "Id template when no ids file is given")
+	fs.Float64Var(&options.rate, "rate", 10, "Target messages per second")
+	fs.DurationVar(&options.duration, "duration", time.Minute, "Test duration")
+	fs.DurationVar(&options.rampUp, "ramp-up", 0, "Time to increase the rate linearly up to the target")
+	fs.IntVar(&options.concurrency, "c", 8, "Number of concurrent publishers")
+	authFlags(fs, &options.username, &options.authorities)
+	fs.BoolVar(&options.verbose, "v", false, "Verbose")
+	fs.BoolVar(&options.help, "help", false, "Help")
+	brokerFlags(fs)
+	return fs
+}
+
+// load publishes the pull messages of the options.command command of registry
+// at the target rate
+// until the duration elapses or ctx is cancelled, writing a stats line to out
+// every second. The report of the messages sent is also returned when the ids
+// fail or any message is not published.
+func load(ctx context.Context, publisher client.Publisher, registry *Registry, options *loadOptions, out io.Writer) (*loadReport, error) {
+	next, err := loadMessages(registry, options)
+	if err != nil {
+		return nil, err
+	}
+	if options.rate <= 0 || options.concurrency <= 0 {
+		return nil, fmt.Errorf("rate and concurrency must be positive")
+	}
+	nextId, err := loadIds(options.idsFile, options.generator)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &loadReport{}
+	var mutex sync.Mutex
+	var sendErr error
+	sends := make(chan loadSend, options.concurrency)
+	var wg sync.WaitGroup
+	for w := 0; w < options.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for send := range sends {
+				// Messages queued when the test is interrupted are not sent
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := send(ctx, publisher); err != nil && ctx.Err() == nil {
+					atomic.AddInt64(&report.Errors, 1)
+					mutex.Lock()
+					sendErr = err
+					mutex.Unlock()
+					if options.verbose {
+						log.Printf("Error publishing message: %s", err)
+					}
+				}
+				atomic.AddInt64(&report.Sent, 1)
+			}
+		}()
+	}
+
+	started := time.Now()
+	ticker := time.NewTicker(loadTick)
+	defer ticker.Stop()
+	stats := time.NewTicker(time.Second)
+	defer stats.Stop()
+	queued := 0
+	var idErr error
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-stats.C:
+			elapsed := time.Since(started)
+			fmt.Fprintf(out, "\r%6s  target %7.1f msg/s  actual %7.1f msg/s  sent %8d  errors %6d",
+				elapsed.Round(time.Second), loadRate(elapsed, options.rate, options.rampUp),
+				float64(atomic.LoadInt64(&report.Sent))/elapsed.Seconds(), atomic.LoadInt64(&report.Sent), atomic.LoadInt64(&report.Errors))
+		case <-ticker.C:
+			elapsed := time.Since(started)
+			if elapsed > options.duration {
+				elapsed = options.duration
+			}
+			for due := loadDue(elapsed, options.rate, options.rampUp); queued < due; queued++ {
+				id, err := nextId(queued + 1)
+				if err != nil {
+					idErr = err
+					break loop
+				}
+				send, err := next(id)
+				if err != nil {
+					idErr = err
+					break loop
+				}
+				select {
+				case sends <- send:
+				case <-ctx.Done():
+					break loop
+				}
+			}
+			if elapsed == options.duration {
+				break loop
+			}
+		}
+	}
+	close(sends)
+	wg.Wait()
+	report.Elapsed = time.Since(started)
+	fmt.Fprintln(out)
+	if idErr == nil && report.Errors > 0 {
+		return report, fmt.Errorf("%d of %d messages failed: %w", report.Errors, report.Sent, sendErr)
+	}
+	return report, idErr
+}
+
+// loadSend publishes a message of a pull command.
+type loadSend func(ctx context.Context, publisher client.Publisher) error
+
+// loadTarget is implemented by the pull commands load can publish. Messages
+// are built by the commands themselves, so their bodies and headers are the
+// real ones. loadSender binds the flags of the command to a single options
+// value and returns them with a function that copies the current options
+// into the send of a message.
+type loadTarget interface {
+	loadSender() (*flag.FlagSet, func() loadSend)
+}
+
+// loadMessages returns the builder of the message sent for each id, which is
+// set as the value of the options.field flag of the pull command. An empty
+// field sends the same message every time.
+func loadMessages(registry *Registry, options *loadOptions) (func(id string) (loadSend, error), error) {
+	module, _ := registry.Lookup(options.command)
+	target, ok := module.(loadTarget)
+	if !ok {
+		return nil, fmt.Errorf("unknown pull command '%s'", options.command)
+	}
+	fs, snapshot := target.loadSender()
+	fs.Init(options.command, flag.ContinueOnError)
+	values := map[string]string{"u": options.username, "a": options.authorities}
+	if options.verbose {
+		values["v"] = "true"
+	}
+	for name, value := range values {
+		if fs.Lookup(name) != nil && value != "" {
+			fs.Set(name, value)
+		}
+	}
+	if options.field != "" && fs.Lookup(options.field) == nil {
+		return nil, fmt.Errorf("%s has no -%s flag, use -field '' to send messages without ids", options.command, options.field)
+	}
+	return func(id string) (loadSend, error) {
+		if options.field != "" {
+			if err := fs.Set(options.field, id); err != nil {
+				return nil, err
+			}
+		}
+		return snapshot(), nil
+	}, nil
+}
+
+// loadRate is the target rate after elapsed, increasing linearly during the
+// ramp-up.
+func loadRate(elapsed time.Duration, rate float64, rampUp time.Duration) float64 {
+	if elapsed < rampUp {
+		return rate * elapsed.Seconds() / rampUp.Seconds()
+	}
+	return rate
+}
+
+// loadDue returns how many messages should have been sent after elapsed, the
+// integral of loadRate.
+func loadDue(elapsed time.Duration, rate float64, rampUp time.Duration) int {
+	t := elapsed.Seconds()
+	if elapsed < rampUp {
+		return int(rate * t * t / (2 * rampUp.Seconds()))
+	}
+	return int(rate*rampUp.Seconds()/2 + rate*(t-rampUp.Seconds()))
+}
+
+// loadIds returns the source of the id of each message: the lines of file in
+// a loop or, without file, the generator template rendered with the message
+// index and the uuid and randInt functions.
+func loadIds(file string, generator string) (func(index int) (string, error), error) {
+	if file == "" {
+		tmpl, err := template.New(LoadCmd).Funcs(payloadFuncs).Parse(generator)
+		if err != nil {
+			return nil, err
+		}
+		return func(index int) (string, error) {
+			var id bytes.Buffer
+			err := tmpl.Execute(&id, payloadData{Index: index})
+			return id.String(), err
+		}, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no ids in %s", file)
+	}
+	return func(index int) (string, error) {
+		return ids[(index-1)%len(ids)], nil
+	}, nil
+}
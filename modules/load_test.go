@@ -0,0 +1,87 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestLoadDue(t *testing.T) {
+	tests := []struct {
+		elapsed time.Duration
+		rampUp  time.Duration
+		due     int
+	}{
+		{10 * time.Second, 0, 1000},
+		{5 * time.Second, 10 * time.Second, 125},
+		{10 * time.Second, 10 * time.Second, 500},
+		{20 * time.Second, 10 * time.Second, 1500},
+	}
+	for _, test := range tests {
+		if due := loadDue(test.elapsed, 100, test.rampUp); due != test.due {
+			t.Errorf("%s with ramp-up %s: expected %d, got %d", test.elapsed, test.rampUp, test.due, due)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	ids, remove := tempFile(t, "ids.txt", "C1\nC2\n\nC3\n")
+	defer remove()
+
+	broker := client.NewFakeBroker()
+	registry := DefaultRegistry(broker)
+	options := loadOptions{command: PullCustomersCmd, field: "idcard", idsFile: ids, rate: 100,
+		duration: 200 * time.Millisecond, concurrency: 4, username: "demo", authorities: "demo"}
+	report, err := load(context.Background(), broker, registry, &options, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Sent != 20 || report.Errors != 0 || len(broker.Messages) != 20 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	bodies := make(map[string]int)
+	for _, message := range broker.Messages {
+		if message.Exchange != "cnp.referential" || message.RoutingKey != "customer.pull" {
+			t.Errorf("unexpected destination %s %s", message.Exchange, message.RoutingKey)
+		}
+		bodies[message.Body]++
+	}
+	if bodies[`{"id": "","externalCode": "","idCard": "C1"}`] != 7 || bodies[`{"id": "","externalCode": "","idCard": "C3"}`] != 6 {
+		t.Errorf("unexpected bodies %v", bodies)
+	}
+	if headers := broker.Last().Headers; headers["App-Username"] != "demo" {
+		t.Errorf("unexpected headers %v", headers)
+	}
+
+	broker.Fail("cnp.referential", "customer.pull", &client.UnroutableError{Exchange: "cnp.referential", RoutingKey: "customer.pull"})
+	if report, err = load(context.Background(), broker, registry, &options, ioutil.Discard); !errors.Is(err, client.ErrUnroutable) || report.Errors != 20 {
+		t.Errorf("expected unroutable messages, got %v %+v", err, report)
+	}
+
+	options.command = PullCountriesCmd
+	if _, err := load(context.Background(), broker, registry, &options, ioutil.Discard); err == nil {
+		t.Errorf("expected unknown field")
+	}
+	options.field = ""
+	if _, err := load(context.Background(), broker, registry, &options, ioutil.Discard); err != nil || broker.Last().RoutingKey != "country.pull" {
+		t.Errorf("unexpected countries load %v", err)
+	}
+
+	options.command = ReplayCmd
+	if _, err := load(context.Background(), broker, registry, &options, ioutil.Discard); err == nil {
+		t.Errorf("expected unknown pull command")
+	}
+
+	// Every pull command can be used by load
+	for _, endpoint := range Endpoints {
+		module, _ := registry.Lookup(endpoint.Command)
+		if _, ok := module.(loadTarget); ok != strings.HasSuffix(endpoint.RoutingKey, ".pull") {
+			t.Errorf("unexpected load target %s", endpoint.Command)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package modules
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/streadway/amqp"
+	"gopkg.in/yaml.v2"
+)
+
+const MockServiceCmd = "mock-service"
+
+// mockErrorHeader carries the message of the error responses.
+const mockErrorHeader = "App-Error"
+
+type MockServiceModule struct {
+}
+
+type mockServiceOptions struct {
+	file    string
+	verbose bool
+	help    bool
+}
+
+// mockResponse is a canned reply to the requests sent to Exchange with a
+// routing key matching RoutingKey. Requests can also be matched by exact
+// header values and by a regular expression on the body.
+type mockResponse struct {
+	Exchange     string            `yaml:"exchange"`
+	RoutingKey   string            `yaml:"routingKey"`
+	Headers      map[string]string `yaml:"headers,omitempty"`
+	Body         string            `yaml:"body,omitempty"`
+	Delay        time.Duration     `yaml:"delay,omitempty"`
+	Reply        string            `yaml:"reply,omitempty"`
+	ReplyHeaders map[string]string `yaml:"replyHeaders,omitempty"`
+	// Error responses carry the message in the App-Error header
+	Error string `yaml:"error,omitempty"`
+	// Ignore the request, so the caller times out
+	NoReply bool `yaml:"noReply,omitempty"`
+	body    *regexp.Regexp
+}
+
+type mockConfig struct {
+	Responses []mockResponse `yaml:"responses"`
+}
+
+// mockReplier sends a reply to the ReplyTo queue of a request.
+type mockReplier func(ctx context.Context, replyTo string, correlationId string, body string, headers amqp.Table) error
+
+func (m MockServiceModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     MockServiceCmd,
+		Category: CategoryTesting,
+		Short:    "Answer requests with canned responses",
+		Long: `Subscribe to the exchanges of a YAML or JSON file and answer the matching
+requests with canned replies, errors, delays or no reply at all, so the
+request/reply commands can be tested without the real services.`,
+		Examples: []string{"hodei-cli mock-service -f responses.yaml"},
+	}
+}
+
+func (m MockServiceModule) Execute(ctx context.Context, args []string) error {
+	options := mockServiceOptions{}
+	flagset := mockServiceCreateFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return mockService(ctx, &options)
+}
+
+func mockServiceCreateFlagSet(options *mockServiceOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(MockServiceCmd, flag.ExitOnError)
+	fs.StringVar(&options.file, "f", "", "YAML or JSON file with the canned responses")
+	fs.BoolVar(&options.verbose, "v", false, "Verbose")
+	fs.BoolVar(&options.help, "help", false, "Help")
+	brokerFlags(fs)
+	return fs
+}
+
+func mockService(ctx context.Context, options *mockServiceOptions) error {
+	responses, err := readMockResponses(options.file)
+	if err != nil {
+		return err
+	}
+
+	patterns := make(map[string][]string)
+	for _, response := range responses {
+		patterns[response.Exchange] = append(patterns[response.Exchange], response.RoutingKey)
+	}
+	closed := make(chan string, len(patterns))
+	for exchange := range patterns {
+		subscription, err := client.Subscribe(exchange, patterns[exchange]...)
+		if err != nil {
+			return err
+		}
+		defer subscription.Close()
+		log.Printf("Answering requests sent to %s with routing keys %v", exchange, patterns[exchange])
+		go func(exchange string, messages <-chan amqp.Delivery) {
+			for d := range messages {
+				go mockHandle(ctx, responses, d, client.SendReply, options.verbose)
+			}
+			closed <- exchange
+		}(exchange, subscription.Messages)
+	}
+
+	select {
+	case exchange := <-closed:
+		return fmt.Errorf("subscription to %s closed by the broker", exchange)
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func readMockResponses(file string) ([]mockResponse, error) {
+	if file == "" {
+		return nil, fmt.Errorf("required responses file")
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	// JSON documents are valid YAML
+	config := mockConfig{}
+	if err = yaml.UnmarshalStrict(b, &config); err != nil {
+		return nil, err
+	}
+	if len(config.Responses) == 0 {
+		return nil, fmt.Errorf("no responses in %s", file)
+	}
+	for i := range config.Responses {
+		response := &config.Responses[i]
+		if response.Exchange == "" || response.RoutingKey == "" {
+			return nil, fmt.Errorf("response %d: required exchange and routing key", i+1)
+		}
+		if response.Body != "" {
+			if response.body, err = regexp.Compile(response.Body); err != nil {
+				return nil, fmt.Errorf("response %d: %s", i+1, err)
+			}
+		}
+	}
+	return config.Responses, nil
+}
+
+// matches reports whether the response applies to a request. Deliveries
+// carry the exchange renamed by the profile.
+func (r *mockResponse) matches(d *amqp.Delivery) bool {
+	if d.Exchange != client.DefaultConfig().ExchangeName(r.Exchange) || !client.TopicMatches(r.RoutingKey, d.RoutingKey) {
+		return false
+	}
+	for key, value := range r.Headers {
+		if fmt.Sprint(d.Headers[key]) != value {
+			return false
+		}
+	}
+	return r.body == nil || r.body.Match(d.Body)
+}
+
+// mockHandle answers a request with the first matching response. Requests
+// without ReplyTo or without a matching response are ignored.
+func mockHandle(ctx context.Context, responses []mockResponse, d amqp.Delivery, reply mockReplier, verbose bool) {
+	var response *mockResponse
+	for i := range responses {
+		if responses[i].matches(&d) {
+			response = &responses[i]
+			break
+		}
+	}
+	switch {
+	case d.ReplyTo == "":
+		if verbose {
+			log.Printf("Ignoring %s %s without reply-to", d.Exchange, d.RoutingKey)
+		}
+		return
+	case response == nil:
+		log.Printf("No response for %s %s (correlation id %s)", d.Exchange, d.RoutingKey, d.CorrelationId)
+		return
+	case response.NoReply:
+		log.Printf("Ignoring %s %s (correlation id %s)", d.Exchange, d.RoutingKey, d.CorrelationId)
+		return
+	}
+
+	select {
+	case <-time.After(response.Delay):
+	case <-ctx.Done():
+		return
+	}
+	headers := amqp.Table{}
+	for key, value := range response.ReplyHeaders {
+		headers[key] = value
+	}
+	body := response.Reply
+	if response.Error != "" {
+		headers[mockErrorHeader] = response.Error
+		if body == "" {
+			body = response.Error
+		}
+	}
+	if err := reply(ctx, d.ReplyTo, d.CorrelationId, body, headers); err != nil {
+		log.Printf("Error replying to %s %s: %s", d.Exchange, d.RoutingKey, err)
+	} else if verbose {
+		log.Printf("Replied to %s %s (correlation id %s): %s", d.Exchange, d.RoutingKey, d.CorrelationId, body)
+	}
+}
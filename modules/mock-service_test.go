@@ -0,0 +1,74 @@
+package modules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+func TestMockService(t *testing.T) {
+	file, remove := tempFile(t, "responses.yaml", `
+responses:
+  - exchange: cnp.sepa
+    routingKey: iban.validation
+    body: 'ES00'
+    error: Invalid IBAN
+  - exchange: cnp.sepa
+    routingKey: iban.validation
+    headers:
+      App-Username: slow
+    delay: 10ms
+    noReply: true
+  - exchange: cnp.sepa
+    routingKey: iban.*
+    reply: '{"valid":true}'
+    replyHeaders:
+      App-Source: mock
+`)
+	defer remove()
+	responses, err := readMockResponses(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		routingKey string
+		headers    amqp.Table
+		body       string
+		reply      string
+		error      string
+	}{
+		{"iban.validation", amqp.Table{}, `{"iban":"ES91"}`, `{"valid":true}`, ""},
+		{"iban.validation", amqp.Table{}, `{"iban":"ES00"}`, "Invalid IBAN", "Invalid IBAN"},
+		{"iban.validation", amqp.Table{"App-Username": "slow"}, `{"iban":"ES91"}`, "", ""},
+		{"iban.check", amqp.Table{}, `{"iban":"ES91"}`, `{"valid":true}`, ""},
+		{"customer.search", amqp.Table{}, `{}`, "", ""},
+	}
+	for _, test := range tests {
+		var replies []string
+		var headers amqp.Table
+		reply := func(ctx context.Context, replyTo string, correlationId string, body string, h amqp.Table) error {
+			if replyTo != "reply-queue" || correlationId != "1234" {
+				t.Errorf("unexpected reply destination %s %s", replyTo, correlationId)
+			}
+			replies = append(replies, body)
+			headers = h
+			return nil
+		}
+		d := amqp.Delivery{Exchange: "cnp.sepa", RoutingKey: test.routingKey, Headers: test.headers,
+			Body: []byte(test.body), ReplyTo: "reply-queue", CorrelationId: "1234"}
+		mockHandle(context.Background(), responses, d, reply, false)
+		if test.reply == "" {
+			if len(replies) != 0 {
+				t.Errorf("%s %s: unexpected replies %v", test.routingKey, test.body, replies)
+			}
+			continue
+		}
+		if len(replies) != 1 || replies[0] != test.reply {
+			t.Errorf("%s %s: unexpected replies %v", test.routingKey, test.body, replies)
+		} else if test.error != "" && headers[mockErrorHeader] != test.error {
+			t.Errorf("%s %s: unexpected headers %v", test.routingKey, test.body, headers)
+		}
+	}
+}
@@ -1,5 +1,137 @@
 package modules
 
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+// Command categories used to group the usage
+const (
+	CategoryReferential = "Referential synchronization"
+	CategoryServices    = "Service requests"
+	CategoryMessaging   = "Messaging"
+	CategoryBroker      = "Broker administration"
+	CategoryTesting     = "Testing"
+	CategoryMongo       = "MongoDB"
+	CategoryOther       = "Other"
+)
+
+// Categories lists the command categories in usage order.
+var Categories = []string{CategoryReferential, CategoryServices, CategoryMessaging, CategoryBroker, CategoryTesting, CategoryMongo, CategoryOther}
+
+// HodeiCliModule is a cli command. Execute returns the error that made the
+// command fail, whose kind decides the exit code, and must stop when ctx is
+// cancelled.
 type HodeiCliModule interface {
-	Execute(args []string)
+	Info() ModuleInfo
+	Execute(ctx context.Context, args []string) error
+}
+
+// ModuleInfo describes a command for the usage and help messages.
+type ModuleInfo struct {
+	Name     string
+	Category string
+	Short    string
+	Long     string
+	Examples []string
+}
+
+// printHelp prints the description, options and examples of a command.
+func printHelp(info ModuleInfo, fs *flag.FlagSet) {
+	description := info.Long
+	if description == "" {
+		description = info.Short
+	}
+	fmt.Fprintf(fs.Output(), "%s\n\nUsage: hodei-cli %s [OPTIONS]\n\nOptions:\n", description, info.Name)
+	fs.PrintDefaults()
+	if len(info.Examples) > 0 {
+		fmt.Fprintf(fs.Output(), "\nExamples:\n  %s\n", strings.Join(info.Examples, "\n  "))
+	}
+}
+
+// isHelp reports whether arg asks for the help of a command group.
+func isHelp(arg string) bool {
+	switch arg {
+	case "help", "-help", "--help", "-h":
+		return true
+	}
+	return false
+}
+
+// Registry holds the commands of the cli in the order they are listed in the
+// usage.
+type Registry struct {
+	names   []string
+	modules map[string]HodeiCliModule
+}
+
+func NewRegistry() *Registry {
+	return &Registry{modules: make(map[string]HodeiCliModule)}
+}
+
+// DefaultRegistry returns a registry with every command of this package,
+// sending messages through publisher.
+func DefaultRegistry(publisher client.Publisher) *Registry {
+	r := NewRegistry()
+	r.Register(CustomerSearchModule{Publisher: publisher})
+	r.Register(PullCountriesModule{Publisher: publisher})
+	r.Register(PullProductsModule{Publisher: publisher})
+	r.Register(PullAgreementsModule{Publisher: publisher})
+	r.Register(PullNetworksModule{Publisher: publisher})
+	r.Register(PullCustomersModule{Publisher: publisher})
+	r.Register(PullProfessionsModule{Publisher: publisher})
+	r.Register(PullPoliciesModule{Publisher: publisher})
+	r.Register(PullOrdersModule{Publisher: publisher})
+	r.Register(PullCoveragesModule{Publisher: publisher})
+	r.Register(PullClaimsModule{Publisher: publisher})
+	r.Register(CheckIbanModule{Publisher: publisher})
+	r.Register(SignatureRequestModule{Publisher: publisher})
+	r.Register(ListScheduledActionsModule{})
+	r.Register(MongoResetModule{})
+	r.Register(TailModule{})
+	r.Register(ReplayModule{Publisher: publisher})
+	r.Register(PublishModule{Publisher: publisher})
+	r.Register(RpcModule{Publisher: publisher})
+	r.Register(DlqModule{Browser: client.DefaultBrowser})
+	r.Register(TopologyModule{})
+	r.Register(DoctorModule{})
+	r.Register(BenchModule{Publisher: publisher})
+	r.Register(LoadModule{Publisher: publisher, Registry: r})
+	r.Register(MockServiceModule{})
+	r.Register(VerifyModule{Browser: client.DefaultBrowser})
+	return r
+}
+
+// Register adds a command, replacing any previous one with the same name.
+func (r *Registry) Register(module HodeiCliModule) {
+	name := module.Info().Name
+	if _, ok := r.modules[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.modules[name] = module
+}
+
+func (r *Registry) Lookup(name string) (HodeiCliModule, bool) {
+	module, ok := r.modules[name]
+	return module, ok
+}
+
+// Category returns the commands of a category in registration order.
+func (r *Registry) Category(category string) []string {
+	var names []string
+	for _, name := range r.names {
+		if r.modules[name].Info().Category == category {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Names returns the registered commands in registration order.
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.names...)
 }
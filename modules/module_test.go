@@ -0,0 +1,63 @@
+package modules
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestRegistry(t *testing.T) {
+	broker := client.NewFakeBroker()
+	registry := DefaultRegistry(broker)
+	for _, endpoint := range Endpoints {
+		if _, ok := registry.Lookup(endpoint.Command); !ok {
+			t.Errorf("command %s is not registered", endpoint.Command)
+		}
+	}
+	if names := registry.Names(); len(names) != len(registry.modules) {
+		t.Errorf("unexpected names %v", names)
+	}
+
+	for _, category := range Categories {
+		for _, name := range registry.Category(category) {
+			if module, _ := registry.Lookup(name); module.Info().Short == "" {
+				t.Errorf("command %s has no description", name)
+			}
+		}
+	}
+
+	module, _ := registry.Lookup(PullCountriesCmd)
+	if err := module.Execute(context.Background(), []string{}); err != nil {
+		t.Error(err)
+	}
+	if last := broker.Last(); last == nil || last.RoutingKey != "country.pull" {
+		t.Errorf("unexpected message %+v", last)
+	}
+
+	module, _ = registry.Lookup(CheckIbanCmd)
+	if err := module.Execute(context.Background(), []string{}); err == nil {
+		t.Error("check-iban without arguments did not fail")
+	}
+}
+
+// tempFile returns the path of name in a new temporary directory, writing
+// content to it unless it is empty, and a function removing the directory.
+func tempFile(t *testing.T, name string, content string) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "hodei")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, name)
+	if content != "" {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	return file, func() { os.RemoveAll(dir) }
+}
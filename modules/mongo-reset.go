@@ -4,7 +4,6 @@ import (
 	"context"
 	"flag"
 	"log"
-	"os"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,6 +12,21 @@ import (
 )
 
 const MongoResetCmd = "mongo-reset"
+const defaultMongoUri = "mongodb://localhost:27017"
+
+// mongoCollections maps the collections owned by the microservices to their
+// databases.
+var mongoCollections = map[string]string{
+	"actions":             "cnp-actions",
+	"scheduledActions":    "cnp-actions",
+	"persons":             "cnp-customers",
+	"policies":            "ppi-policies",
+	"documentCollections": "cnp-documents",
+	"policyOrders":        "cnp-orders",
+	"claims":              "cnp-claims",
+	"coverages":           "cnp-coverages",
+	"orders":              "cnp-orders",
+}
 
 type MongoResetModule struct {
 }
@@ -23,34 +37,44 @@ type mongoExecutionOptions struct {
 	help    bool
 }
 
-func (m MongoResetModule) Execute(args []string) {
+func (m MongoResetModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     MongoResetCmd,
+		Category: CategoryMongo,
+		Short:    "Remove the documents of the microservice collections",
+		Long: `Remove every document of the collections owned by the microservices, using the
+database of the profile, APP_MONGO_URI or mongodb://localhost:27017.`,
+		Examples: []string{"hodei-cli mongo-reset -v"},
+	}
+}
+
+func (m MongoResetModule) Execute(ctx context.Context, args []string) error {
 	options := mongoExecutionOptions{}
 	flagset := mongoResetCreateFlagSet(&options)
 	flagset.Parse(args)
 
-	if flagset.Parsed() {
-		if options.help {
-			flagset.PrintDefaults()
-		} else {
-			mongoReset(&options)
-		}
+	if options.help {
+		printHelp(m.Info(), flagset)
+		return nil
 	}
+	return mongoReset(ctx, &options)
 }
 
 func mongoResetCreateFlagSet(options *mongoExecutionOptions) *flag.FlagSet {
 	fs := flag.NewFlagSet(MongoResetCmd, flag.ExitOnError)
 	fs.BoolVar(&options.verbose, "v", false, "Verbose")
 	fs.BoolVar(&options.help, "help", false, "Help")
-	fs.StringVar(&options.url, "url", "", "Mongo uri (optional. Default mongodb://localhost:27017)")
+	fs.StringVar(&options.url, "url", "", "Mongo uri (optional. Default the profile uri, APP_MONGO_URI or mongodb://localhost:27017)")
 	return fs
 }
 
-func mongoReset(cmdOptions *mongoExecutionOptions) {
-	mongoUri, definedUri := os.LookupEnv("APP_MONGO_URI")
-
-	//TODO read argument if defined
-	if !definedUri {
-		mongoUri = "mongodb://localhost:27017"
+func mongoReset(ctx context.Context, cmdOptions *mongoExecutionOptions) error {
+	mongoUri := cmdOptions.url
+	if mongoUri == "" {
+		mongoUri = mongoUriValue()
+	}
+	if mongoUri == "" {
+		mongoUri = defaultMongoUri
 		log.Printf("Using default mongo URI %s", mongoUri)
 	}
 
@@ -58,32 +82,24 @@ func mongoReset(cmdOptions *mongoExecutionOptions) {
 		log.Printf("Cleaning documents")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	connectCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoUri))
+	client, err := mongo.Connect(connectCtx, options.Client().ApplyURI(mongoUri))
 	if err != nil {
-		log.Fatalf("%s: %s", "Error opening connection", err)
-		os.Exit(1)
+		return err
 	}
+	defer client.Disconnect(context.Background())
 
-	collectionMap := map[string]string{
-		"actions":             "cnp-actions",
-		"scheduledActions":    "cnp-actions",
-		"persons":             "cnp-customers",
-		"policies":            "ppi-policies",
-		"documentCollections": "cnp-documents",
-		"policyOrders":        "cnp-orders",
-		"claims":              "cnp-claims",
-		"coverages":           "cnp-coverages",
-		"orders":              "cnp-orders",
-	}
-	for table, database := range collectionMap {
+	for table, database := range mongoCollections {
 		log.Printf("Removing documents from %s.%s", database, table)
-		client.Database(database).Collection(table).DeleteMany(context.Background(), bson.D{})
+		if _, err := client.Database(database).Collection(table).DeleteMany(ctx, bson.D{}); err != nil {
+			return err
+		}
 	}
 
 	if cmdOptions.verbose {
 		log.Printf("Reset complete")
 	}
+	return nil
 }
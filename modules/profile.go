@@ -0,0 +1,133 @@
+package modules
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"gopkg.in/yaml.v2"
+)
+
+// Profile is a named environment of the config file. Its values replace the
+// APP_AMQP_URI and APP_MONGO_URI variables and are the defaults of the -u and
+// -a flags. Exchanges renames the exchanges used by the commands and
+// SigningKey replaces APP_AMQP_SIGNING_KEY.
+type Profile struct {
+	Name         string            `yaml:"-"`
+	AmqpUri      string            `yaml:"amqpUri,omitempty"`
+	MongoUri     string            `yaml:"mongoUri,omitempty"`
+	Username     string            `yaml:"username,omitempty"`
+	Authorities  string            `yaml:"authorities,omitempty"`
+	Exchanges    map[string]string `yaml:"exchanges,omitempty"`
+	SigningKey   string            `yaml:"signingKey,omitempty"`
+	SigningKeyId string            `yaml:"signingKeyId,omitempty"`
+}
+
+type profileConfig struct {
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// activeProfile is the profile selected for the running command, empty when
+// none is selected.
+var activeProfile Profile
+
+// ConfigFile returns the file with the profiles, HODEI_CONFIG or
+// ~/.hodei/config.yaml.
+func ConfigFile() string {
+	if file := os.Getenv("HODEI_CONFIG"); file != "" {
+		return file
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".hodei", "config.yaml")
+}
+
+// ProfileArgs removes the global --profile flag placed before the command
+// from args and returns the selected profile, which defaults to the
+// HODEI_PROFILE variable.
+func ProfileArgs(args []string) (string, []string) {
+	profile := os.Getenv("HODEI_PROFILE")
+	for len(args) > 0 {
+		switch arg := args[0]; {
+		case (arg == "--profile" || arg == "-profile") && len(args) > 1:
+			profile, args = args[1], args[2:]
+		case strings.HasPrefix(arg, "--profile="), strings.HasPrefix(arg, "-profile="):
+			profile, args = arg[strings.Index(arg, "=")+1:], args[1:]
+		default:
+			return profile, args
+		}
+	}
+	return profile, args
+}
+
+// LoadProfile reads the profile name from file.
+func LoadProfile(file string, name string) (Profile, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return Profile{}, fmt.Errorf("reading profile %s: %s", name, err)
+	}
+	config := profileConfig{}
+	if err = yaml.UnmarshalStrict(b, &config); err != nil {
+		return Profile{}, fmt.Errorf("reading %s: %s", file, err)
+	}
+	profile, ok := config.Profiles[name]
+	if !ok {
+		var names []string
+		for name := range config.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Profile{}, fmt.Errorf("unknown profile '%s', defined profiles are %v", name, names)
+	}
+	profile.Name = name
+	return profile, nil
+}
+
+// UseProfile applies a profile to the default broker configuration and to
+// the defaults of the command flags. It must be called before the command is
+// executed, so its flags still take precedence.
+func UseProfile(profile Profile) {
+	config := client.DefaultConfig()
+	if profile.AmqpUri != "" {
+		config.Uri = client.AmqpUri(profile.AmqpUri)
+	}
+	config.Exchanges = profile.Exchanges
+	if profile.SigningKey != "" {
+		config.Signing.Key = profile.SigningKey
+	}
+	if profile.SigningKeyId != "" {
+		config.Signing.KeyId = profile.SigningKeyId
+	}
+	activeProfile = profile
+}
+
+// authFlags registers the -u and -a flags, which default to the values of the
+// active profile.
+func authFlags(fs *flag.FlagSet, username *string, authorities *string) {
+	fs.StringVar(username, "u", activeProfile.Username, "Username")
+	fs.StringVar(authorities, "a", activeProfile.Authorities, "Authorities")
+}
+
+// amqpUriValue returns the AMQP URI of the active profile or APP_AMQP_URI.
+func amqpUriValue() string {
+	if activeProfile.AmqpUri != "" {
+		return activeProfile.AmqpUri
+	}
+	return os.Getenv("APP_AMQP_URI")
+}
+
+// mongoUriValue returns the MongoDB URI of the active profile or
+// APP_MONGO_URI, empty when neither is defined.
+func mongoUriValue() string {
+	if activeProfile.MongoUri != "" {
+		return activeProfile.MongoUri
+	}
+	return os.Getenv("APP_MONGO_URI")
+}
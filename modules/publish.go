@@ -0,0 +1,94 @@
+package modules
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/streadway/amqp"
+)
+
+const PublishCmd = "publish"
+
+type PublishModule struct {
+	Publisher client.Publisher
+}
+
+type publishOptions struct {
+	exchange   string
+	routingKey string
+	headers    headerFlags
+	body       string
+	file       string
+	verbose    bool
+	help       bool
+}
+
+func (m PublishModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     PublishCmd,
+		Category: CategoryMessaging,
+		Short:    "Publish any message to an exchange and routing key",
+		Examples: []string{"hodei-cli publish -exchange cnp.referential -key country.pull -H App-Username=demo -body '{}'"},
+	}
+}
+
+func (m PublishModule) Execute(ctx context.Context, args []string) error {
+	options := publishOptions{headers: headerFlags{}}
+	flagset := publishCreateFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return reportPublish(publish(ctx, m.Publisher, &options))
+}
+
+func publishCreateFlagSet(options *publishOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(PublishCmd, flag.ExitOnError)
+	messageFlags(fs, &options.exchange, &options.routingKey, options.headers, &options.body, &options.file)
+	fs.BoolVar(&options.verbose, "v", false, "Verbose")
+	fs.BoolVar(&options.help, "help", false, "Help")
+	brokerFlags(fs)
+	return fs
+}
+
+func publish(ctx context.Context, publisher client.Publisher, options *publishOptions) error {
+	if options.exchange == "" && options.routingKey == "" {
+		return fmt.Errorf("required exchange or routing key")
+	}
+	body, err := readBody(options.body, options.file)
+	if err != nil {
+		return err
+	}
+	return publisher.SendMessageWithHeaders(ctx, options.exchange, options.routingKey, body, amqp.Table(options.headers), options.verbose)
+}
+
+// messageFlags registers the destination, headers and body options shared by
+// the generic publish and rpc commands.
+func messageFlags(fs *flag.FlagSet, exchange *string, routingKey *string, headers headerFlags, body *string, file *string) {
+	fs.StringVar(exchange, "exchange", "", "Exchange")
+	fs.StringVar(routingKey, "key", "", "Routing key")
+	fs.Var(headers, "H", "Message header key=value (repeatable)")
+	fs.StringVar(body, "body", "", "Message body")
+	fs.StringVar(file, "file", "", "Read the message body from a file, - for stdin")
+}
+
+func readBody(body string, file string) (string, error) {
+	if body != "" && file != "" {
+		return "", fmt.Errorf("body and file are mutually exclusive")
+	}
+	switch file {
+	case "":
+		return body, nil
+	case "-":
+		content, err := ioutil.ReadAll(os.Stdin)
+		return string(content), err
+	}
+	content, err := ioutil.ReadFile(file)
+	return string(content), err
+}
@@ -0,0 +1,21 @@
+package modules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestPublish(t *testing.T) {
+	broker := client.NewFakeBroker()
+	options := publishOptions{exchange: "cnp.referential", routingKey: "country.pull", headers: headerFlags{}, body: "{}"}
+	options.headers.Set("App-Username=demo")
+	if err := publish(context.Background(), broker, &options); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	msg := broker.Last()
+	if msg.Exchange != "cnp.referential" || msg.RoutingKey != "country.pull" || msg.Body != "{}" || msg.Headers["App-Username"] != "demo" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"log"
 
@@ -18,9 +19,42 @@ type PullAgreementsOptions struct {
 	Help         bool
 }
 
+type PullAgreementsModule struct {
+	Publisher client.Publisher
+}
+
+func (m PullAgreementsModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     PullAgreementsCmd,
+		Category: CategoryReferential,
+		Short:    "Send an agreements synchronization message",
+		Examples: []string{"hodei-cli pull-agreements -id 20725 -u demo -a demo"},
+	}
+}
+
+func (m PullAgreementsModule) Execute(ctx context.Context, args []string) error {
+	options := PullAgreementsOptions{}
+	flagset := PullAgreementsFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return reportPublish(PullAgreements(ctx, m.Publisher, &options))
+}
+
 const PullAgreementsCmd = "pull-agreements"
 
-func PullAgreements(options *PullAgreementsOptions) {
+func (m PullAgreementsModule) loadSender() (*flag.FlagSet, func() loadSend) {
+	options := PullAgreementsOptions{}
+	return PullAgreementsFlagSet(&options), func() loadSend {
+		snapshot := options
+		return func(ctx context.Context, p client.Publisher) error { return PullAgreements(ctx, p, &snapshot) }
+	}
+}
+
+func PullAgreements(ctx context.Context, publisher client.Publisher, options *PullAgreementsOptions) error {
 	if options.Verbose {
 		log.Printf("Pulling agreements from referential API")
 	}
@@ -29,7 +63,7 @@ func PullAgreements(options *PullAgreementsOptions) {
 		"App-Authorities": options.Authorities,
 	}
 	body := `{"id": "` + options.Id + `","externalCode": "` + options.ExternalCode + `"}`
-	client.SendMessageWithHeaders("cnp.referential", "agreement.pull", body, headers, options.Verbose)
+	return publisher.SendMessageWithHeaders(ctx, "cnp.referential", "agreement.pull", body, headers, options.Verbose)
 }
 
 func PullAgreementsFlagSet(options *PullAgreementsOptions) *flag.FlagSet {
@@ -37,9 +71,9 @@ func PullAgreementsFlagSet(options *PullAgreementsOptions) *flag.FlagSet {
 	fs.StringVar(&options.Id, "id", "", "Agreement identifier")
 	fs.StringVar(&options.ExternalCode, "externalcode", "", "Agreement external code")
 	fs.StringVar(&options.ExternalCode, "product", "", "Product identifier")
-	fs.StringVar(&options.Username, "u", "", "Username")
-	fs.StringVar(&options.Authorities, "a", "", "Authorities")
+	authFlags(fs, &options.Username, &options.Authorities)
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
 	return fs
 }
@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"log"
 
@@ -21,7 +22,40 @@ type PullClaimsOptions struct {
 	Help               bool
 }
 
-func PullClaims(options *PullClaimsOptions) (res string, err error) {
+type PullClaimsModule struct {
+	Publisher client.Publisher
+}
+
+func (m PullClaimsModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     PullClaimsCmd,
+		Category: CategoryReferential,
+		Short:    "Send a claims synchronization message",
+		Examples: []string{"hodei-cli pull-claims -id 1234 -u demo -a demo"},
+	}
+}
+
+func (m PullClaimsModule) Execute(ctx context.Context, args []string) error {
+	options := PullClaimsOptions{}
+	flagset := PullClaimsFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return reportPublish(PullClaims(ctx, m.Publisher, &options))
+}
+
+func (m PullClaimsModule) loadSender() (*flag.FlagSet, func() loadSend) {
+	options := PullClaimsOptions{}
+	return PullClaimsFlagSet(&options), func() loadSend {
+		snapshot := options
+		return func(ctx context.Context, p client.Publisher) error { return PullClaims(ctx, p, &snapshot) }
+	}
+}
+
+func PullClaims(ctx context.Context, publisher client.Publisher, options *PullClaimsOptions) error {
 	if options.Verbose {
 		log.Printf("Pulling claims from referential API")
 	}
@@ -34,8 +68,7 @@ func PullClaims(options *PullClaimsOptions) (res string, err error) {
 		`","policyId":"` + options.PolicyId +
 		`","policyExternalCode":"` + options.PolicyExternalCode +
 		`"}`
-	client.SendMessageWithHeaders("cnp.referential", "claim.pull", body, headers, options.Verbose)
-	return
+	return publisher.SendMessageWithHeaders(ctx, "cnp.referential", "claim.pull", body, headers, options.Verbose)
 }
 
 func PullClaimsFlagSet(options *PullClaimsOptions) *flag.FlagSet {
@@ -45,8 +78,8 @@ func PullClaimsFlagSet(options *PullClaimsOptions) *flag.FlagSet {
 	fs.StringVar(&options.PolicyId, "policyid", "", "Policy identifier")
 	fs.StringVar(&options.PolicyExternalCode, "policyexternalcode", "", "Policy external code")
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
-	fs.StringVar(&options.Username, "u", "", "Username")
-	fs.StringVar(&options.Authorities, "a", "", "Authorities")
+	authFlags(fs, &options.Username, &options.Authorities)
 	return fs
 }
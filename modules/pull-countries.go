@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"log"
 
@@ -14,16 +15,50 @@ type PullCountriesOptions struct {
 	Help    bool
 }
 
-func PullCountries(options *PullCountriesOptions) {
+type PullCountriesModule struct {
+	Publisher client.Publisher
+}
+
+func (m PullCountriesModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     PullCountriesCmd,
+		Category: CategoryReferential,
+		Short:    "Send a countries synchronization message",
+		Examples: []string{"hodei-cli pull-countries"},
+	}
+}
+
+func (m PullCountriesModule) Execute(ctx context.Context, args []string) error {
+	options := PullCountriesOptions{}
+	flagset := PullCountriesFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return reportPublish(PullCountries(ctx, m.Publisher, &options))
+}
+
+func (m PullCountriesModule) loadSender() (*flag.FlagSet, func() loadSend) {
+	options := PullCountriesOptions{}
+	return PullCountriesFlagSet(&options), func() loadSend {
+		snapshot := options
+		return func(ctx context.Context, p client.Publisher) error { return PullCountries(ctx, p, &snapshot) }
+	}
+}
+
+func PullCountries(ctx context.Context, publisher client.Publisher, options *PullCountriesOptions) error {
 	if options.Verbose {
 		log.Printf("Pulling countries from referential API")
 	}
-	client.SendMessage("cnp.referential", "country.pull", "", options.Verbose)
+	return publisher.SendMessageWithHeaders(ctx, "cnp.referential", "country.pull", "", nil, options.Verbose)
 }
 
 func PullCountriesFlagSet(options *PullCountriesOptions) *flag.FlagSet {
 	fs := flag.NewFlagSet(PullCountriesCmd, flag.ExitOnError)
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
 	return fs
 }
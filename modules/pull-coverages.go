@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"log"
 
@@ -21,7 +22,40 @@ type PullCoveragesOptions struct {
 	Help               bool
 }
 
-func PullCoverages(options *PullCoveragesOptions) (res string, err error) {
+type PullCoveragesModule struct {
+	Publisher client.Publisher
+}
+
+func (m PullCoveragesModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     PullCoveragesCmd,
+		Category: CategoryReferential,
+		Short:    "Send a coverages synchronization message",
+		Examples: []string{"hodei-cli pull-coverages -id 1234 -u demo -a demo"},
+	}
+}
+
+func (m PullCoveragesModule) Execute(ctx context.Context, args []string) error {
+	options := PullCoveragesOptions{}
+	flagset := PullCoveragesFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return reportPublish(PullCoverages(ctx, m.Publisher, &options))
+}
+
+func (m PullCoveragesModule) loadSender() (*flag.FlagSet, func() loadSend) {
+	options := PullCoveragesOptions{}
+	return PullCoveragesFlagSet(&options), func() loadSend {
+		snapshot := options
+		return func(ctx context.Context, p client.Publisher) error { return PullCoverages(ctx, p, &snapshot) }
+	}
+}
+
+func PullCoverages(ctx context.Context, publisher client.Publisher, options *PullCoveragesOptions) error {
 	if options.Verbose {
 		log.Printf("Pulling coverages from referential API")
 	}
@@ -34,8 +68,7 @@ func PullCoverages(options *PullCoveragesOptions) (res string, err error) {
 		`","policyId":"` + options.PolicyId +
 		`","policyExternalCode":"` + options.PolicyExternalCode +
 		`"}`
-	client.SendMessageWithHeaders("cnp.referential", "coverage.pull", body, headers, options.Verbose)
-	return
+	return publisher.SendMessageWithHeaders(ctx, "cnp.referential", "coverage.pull", body, headers, options.Verbose)
 }
 
 func PullCoveragesFlagSet(options *PullCoveragesOptions) *flag.FlagSet {
@@ -45,8 +78,8 @@ func PullCoveragesFlagSet(options *PullCoveragesOptions) *flag.FlagSet {
 	fs.StringVar(&options.PolicyId, "policyid", "", "Policy identifier")
 	fs.StringVar(&options.PolicyExternalCode, "policyexternalcode", "", "Policy external code")
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
-	fs.StringVar(&options.Username, "u", "", "Username")
-	fs.StringVar(&options.Authorities, "a", "", "Authorities")
+	authFlags(fs, &options.Username, &options.Authorities)
 	return fs
 }
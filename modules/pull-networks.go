@@ -1,10 +1,10 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/labcabrera/hodei-cli/client"
 	"github.com/streadway/amqp"
@@ -22,24 +22,54 @@ type PullNetworksOptions struct {
 	Help         bool
 }
 
-func PullNetworks(options *PullNetworksOptions) {
+type PullNetworksModule struct {
+	Publisher client.Publisher
+}
+
+func (m PullNetworksModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     PullNetworksCmd,
+		Category: CategoryReferential,
+		Short:    "Send a networks synchronization message",
+		Examples: []string{"hodei-cli pull-networks -idcard 70111222A -u demo -a demo"},
+	}
+}
+
+func (m PullNetworksModule) Execute(ctx context.Context, args []string) error {
+	options := PullNetworksOptions{}
+	flagset := PullNetworksFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return reportPublish(PullNetworks(ctx, m.Publisher, &options))
+}
+
+func (m PullNetworksModule) loadSender() (*flag.FlagSet, func() loadSend) {
+	options := PullNetworksOptions{}
+	return PullNetworksFlagSet(&options), func() loadSend {
+		snapshot := options
+		return func(ctx context.Context, p client.Publisher) error { return PullNetworks(ctx, p, &snapshot) }
+	}
+}
+
+func PullNetworks(ctx context.Context, publisher client.Publisher, options *PullNetworksOptions) error {
 	if options.Verbose {
 		log.Printf("Pulling networks")
 	}
 	if options.Id == "" && options.ExternalCode == "" && options.IdCard == "" {
-		fmt.Println("Required one pull search method parameter")
-		os.Exit(1)
+		return fmt.Errorf("required one pull search method parameter")
 	} else if options.Username == "" || options.Authorities == "" {
-		fmt.Println("Required authentication arguments")
-		os.Exit(1)
+		return fmt.Errorf("required authentication arguments")
 	}
 	headers := amqp.Table{
 		"App-Username":    options.Username,
 		"App-Authorities": options.Authorities,
 	}
 	body := `{"id": "` + options.Id + `","externalCode": "` + options.ExternalCode + `","idCard": "` + options.IdCard + `"}`
-	client.SendMessageWithHeaders("cnp.referential", "network.pull", body, headers, options.Verbose)
-	return
+	return publisher.SendMessageWithHeaders(ctx, "cnp.referential", "network.pull", body, headers, options.Verbose)
 }
 
 func PullNetworksFlagSet(options *PullNetworksOptions) *flag.FlagSet {
@@ -47,9 +77,9 @@ func PullNetworksFlagSet(options *PullNetworksOptions) *flag.FlagSet {
 	fs.StringVar(&options.Id, "id", "", "Entity identifier")
 	fs.StringVar(&options.ExternalCode, "externalcode", "", "Entity external code")
 	fs.StringVar(&options.IdCard, "idcard", "", "Entity IdCard")
-	fs.StringVar(&options.Username, "u", "", "Username")
-	fs.StringVar(&options.Authorities, "a", "", "Authorities")
+	authFlags(fs, &options.Username, &options.Authorities)
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
 	return fs
 }
@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"log"
 
@@ -21,7 +22,40 @@ type PullOrdersOptions struct {
 	Help               bool
 }
 
-func PullOrders(options *PullOrdersOptions) (res string, err error) {
+type PullOrdersModule struct {
+	Publisher client.Publisher
+}
+
+func (m PullOrdersModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     PullOrdersCmd,
+		Category: CategoryReferential,
+		Short:    "Send an orders synchronization message",
+		Examples: []string{"hodei-cli pull-orders -id 1234 -u demo -a demo"},
+	}
+}
+
+func (m PullOrdersModule) Execute(ctx context.Context, args []string) error {
+	options := PullOrdersOptions{}
+	flagset := PullOrdersFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return reportPublish(PullOrders(ctx, m.Publisher, &options))
+}
+
+func (m PullOrdersModule) loadSender() (*flag.FlagSet, func() loadSend) {
+	options := PullOrdersOptions{}
+	return PullOrdersFlagSet(&options), func() loadSend {
+		snapshot := options
+		return func(ctx context.Context, p client.Publisher) error { return PullOrders(ctx, p, &snapshot) }
+	}
+}
+
+func PullOrders(ctx context.Context, publisher client.Publisher, options *PullOrdersOptions) error {
 	if options.Verbose {
 		log.Printf("Pulling orders from referential API")
 	}
@@ -34,8 +68,7 @@ func PullOrders(options *PullOrdersOptions) (res string, err error) {
 		`","policyId":"` + options.PolicyId +
 		`","policyExternalCode":"` + options.PolicyExternalCode +
 		`"}`
-	client.SendMessageWithHeaders("cnp.referential", "order.pull", body, headers, options.Verbose)
-	return
+	return publisher.SendMessageWithHeaders(ctx, "cnp.referential", "order.pull", body, headers, options.Verbose)
 }
 
 func PullOrdersFlagSet(options *PullOrdersOptions) *flag.FlagSet {
@@ -45,8 +78,8 @@ func PullOrdersFlagSet(options *PullOrdersOptions) *flag.FlagSet {
 	fs.StringVar(&options.PolicyId, "policyid", "", "Policy identifier")
 	fs.StringVar(&options.PolicyExternalCode, "policyexternalcode", "", "Policy external code")
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
-	fs.StringVar(&options.Username, "u", "", "Username")
-	fs.StringVar(&options.Authorities, "a", "", "Authorities")
+	authFlags(fs, &options.Username, &options.Authorities)
 	return fs
 }
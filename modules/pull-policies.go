@@ -1,9 +1,9 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"os"
 
 	"github.com/labcabrera/hodei-cli/client"
 	"github.com/streadway/amqp"
@@ -22,26 +22,59 @@ type PullPoliciesOptions struct {
 	Help         bool
 }
 
-func PullPolicies(options *PullPoliciesOptions) {
+type PullPoliciesModule struct {
+	Publisher client.Publisher
+}
+
+func (m PullPoliciesModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     PullPoliciesCmd,
+		Category: CategoryReferential,
+		Short:    "Send a policies synchronization message",
+		Examples: []string{"hodei-cli pull-policies -product ppi -agreement 20725 -u demo -a demo"},
+	}
+}
+
+func (m PullPoliciesModule) Execute(ctx context.Context, args []string) error {
+	options := PullPoliciesOptions{}
+	flagset := PullPoliciesFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return reportPublish(PullPolicies(ctx, m.Publisher, &options))
+}
+
+func (m PullPoliciesModule) loadSender() (*flag.FlagSet, func() loadSend) {
+	options := PullPoliciesOptions{}
+	return PullPoliciesFlagSet(&options), func() loadSend {
+		snapshot := options
+		return func(ctx context.Context, p client.Publisher) error { return PullPolicies(ctx, p, &snapshot) }
+	}
+}
+
+func PullPolicies(ctx context.Context, publisher client.Publisher, options *PullPoliciesOptions) error {
 	if options.Product == "" {
-		fmt.Println("Missing product parameter")
-		os.Exit(1)
+		return fmt.Errorf("missing product parameter")
 	} else if options.Username == "" || options.Authorities == "" {
-		fmt.Println("Missing security parameters")
-		os.Exit(1)
+		return fmt.Errorf("missing security parameters")
 	}
 	productMapping := map[string]string{
 		"ppi": "ppi.referential",
 	}
-	exchange := productMapping[options.Product]
+	exchange, ok := productMapping[options.Product]
+	if !ok {
+		return fmt.Errorf("unknown product '%s'", options.Product)
+	}
 
 	body := `{"id": "` + options.Id + `", "externalCode": "` + options.ExternalCode + `", "agreementId":"` + options.AgreementId + `"}`
 	headers := amqp.Table{
 		"App-Username":    options.Username,
 		"App-Authorities": options.Authorities,
 	}
-	client.SendMessageWithHeaders(exchange, "policy.pull", body, headers, options.Verbose)
-	return
+	return publisher.SendMessageWithHeaders(ctx, exchange, "policy.pull", body, headers, options.Verbose)
 }
 
 func PullPoliciesFlagSet(options *PullPoliciesOptions) *flag.FlagSet {
@@ -50,9 +83,9 @@ func PullPoliciesFlagSet(options *PullPoliciesOptions) *flag.FlagSet {
 	fs.StringVar(&options.Id, "id", "", "Policy identifier")
 	fs.StringVar(&options.ExternalCode, "externalcode", "", "Policy external code")
 	fs.StringVar(&options.AgreementId, "agreement", "", "Agreement identifier")
-	fs.StringVar(&options.Username, "u", "", "Username")
-	fs.StringVar(&options.Authorities, "a", "", "Authorities")
+	authFlags(fs, &options.Username, &options.Authorities)
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
 	return fs
 }
@@ -0,0 +1,19 @@
+package modules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestPullPoliciesUnknownProduct(t *testing.T) {
+	broker := client.NewFakeBroker()
+	err := PullPolicies(context.Background(), broker, &PullPoliciesOptions{Product: "xxx", Username: "u", Authorities: "a"})
+	if err == nil {
+		t.Fatal("expected error for unknown product")
+	}
+	if len(broker.Messages) != 0 {
+		t.Errorf("unexpected messages %v", broker.Messages)
+	}
+}
@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"log"
 
@@ -19,7 +20,40 @@ type PullProductsOptions struct {
 	Help         bool
 }
 
-func PullProducts(options *PullProductsOptions) {
+type PullProductsModule struct {
+	Publisher client.Publisher
+}
+
+func (m PullProductsModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     PullProductsCmd,
+		Category: CategoryReferential,
+		Short:    "Send a products synchronization message",
+		Examples: []string{"hodei-cli pull-products"},
+	}
+}
+
+func (m PullProductsModule) Execute(ctx context.Context, args []string) error {
+	options := PullProductsOptions{}
+	flagset := PullProductsFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return reportPublish(PullProducts(ctx, m.Publisher, &options))
+}
+
+func (m PullProductsModule) loadSender() (*flag.FlagSet, func() loadSend) {
+	options := PullProductsOptions{}
+	return PullProductsFlagSet(&options), func() loadSend {
+		snapshot := options
+		return func(ctx context.Context, p client.Publisher) error { return PullProducts(ctx, p, &snapshot) }
+	}
+}
+
+func PullProducts(ctx context.Context, publisher client.Publisher, options *PullProductsOptions) error {
 	if options.Verbose {
 		log.Printf("Pulling products from referential API")
 	}
@@ -28,7 +62,7 @@ func PullProducts(options *PullProductsOptions) {
 		"App-Authorities": options.Authorities,
 	}
 	body := `{"id": "` + options.Id + `","externalCode": "` + options.ExternalCode + `"}`
-	client.SendMessageWithHeaders("cnp.referential", "product.pull", body, headers, options.Verbose)
+	return publisher.SendMessageWithHeaders(ctx, "cnp.referential", "product.pull", body, headers, options.Verbose)
 }
 
 func PullProductsFlagSet(options *PullProductsOptions) *flag.FlagSet {
@@ -36,8 +70,8 @@ func PullProductsFlagSet(options *PullProductsOptions) *flag.FlagSet {
 	fs.StringVar(&options.Id, "id", "", "Entity identifier")
 	fs.StringVar(&options.ExternalCode, "externalcode", "", "Entity external code")
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
-	fs.StringVar(&options.Username, "u", "", "Username")
-	fs.StringVar(&options.Authorities, "a", "", "Authorities")
+	authFlags(fs, &options.Username, &options.Authorities)
 	return fs
 }
@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"log"
 
@@ -14,16 +15,50 @@ type PullProfessionsOptions struct {
 	Verbose bool
 }
 
-func PullProfessions(options *PullProfessionsOptions) {
+type PullProfessionsModule struct {
+	Publisher client.Publisher
+}
+
+func (m PullProfessionsModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     PullProfessionsCmd,
+		Category: CategoryReferential,
+		Short:    "Send a professions synchronization message",
+		Examples: []string{"hodei-cli pull-professions"},
+	}
+}
+
+func (m PullProfessionsModule) Execute(ctx context.Context, args []string) error {
+	options := PullProfessionsOptions{}
+	flagset := PullProfessionsFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return reportPublish(PullProfessions(ctx, m.Publisher, &options))
+}
+
+func (m PullProfessionsModule) loadSender() (*flag.FlagSet, func() loadSend) {
+	options := PullProfessionsOptions{}
+	return PullProfessionsFlagSet(&options), func() loadSend {
+		snapshot := options
+		return func(ctx context.Context, p client.Publisher) error { return PullProfessions(ctx, p, &snapshot) }
+	}
+}
+
+func PullProfessions(ctx context.Context, publisher client.Publisher, options *PullProfessionsOptions) error {
 	if options.Verbose {
 		log.Printf("Pulling professions from referential API")
 	}
-	client.SendMessage("cnp.referential", "profession.pull", "{}", options.Verbose)
+	return publisher.SendMessageWithHeaders(ctx, "cnp.referential", "profession.pull", "{}", nil, options.Verbose)
 }
 
 func PullProfessionsFlagSet(options *PullProfessionsOptions) *flag.FlagSet {
 	fs := flag.NewFlagSet(PullProfessionsCmd, flag.ExitOnError)
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
 	return fs
 }
@@ -1,9 +1,11 @@
 package modules
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
-	"os"
+	"time"
 
 	"github.com/labcabrera/hodei-cli/client"
 	"github.com/streadway/amqp"
@@ -16,17 +18,43 @@ type CustomerSearchOptions struct {
 	Legal       bool
 	Username    string
 	Authorities string
+	Timeout     time.Duration
+	Collect     client.CollectOptions
 	Verbose     bool
 	Help        bool
 }
 
-func CustomerSearch(options *CustomerSearchOptions) (res string, err error) {
+type CustomerSearchModule struct {
+	Publisher client.Publisher
+}
+
+func (m CustomerSearchModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     CustomerSearchCmd,
+		Category: CategoryServices,
+		Short:    "Search a customer and print its JSON",
+		Examples: []string{"hodei-cli read-customer -id 70111222A -u demo -a demo"},
+	}
+}
+
+func (m CustomerSearchModule) Execute(ctx context.Context, args []string) error {
+	options := CustomerSearchOptions{}
+	flagset := CustomerSearchFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return printReply(CustomerSearch(ctx, m.Publisher, &options))
+}
+
+func CustomerSearch(ctx context.Context, publisher client.Publisher, options *CustomerSearchOptions) (res string, err error) {
 	if options.Verbose {
 		log.Printf("Searching customer %s (%s:%s)", options.Id, options.Username, options.Authorities)
 	}
 	if options.Id == "" {
-		flag.PrintDefaults()
-		os.Exit(1)
+		return "", fmt.Errorf("required customer id")
 	}
 	personType := "person"
 	if options.Legal {
@@ -37,20 +65,20 @@ func CustomerSearch(options *CustomerSearchOptions) (res string, err error) {
 		"App-Authorities": options.Authorities,
 	}
 	body := `{"1":{"type":"` + personType + `","reference":"` + options.Id + `"}}`
-	res, err = client.SendAndReceive("cnp.customer", "customer.search", body, headers, options.Verbose)
-	if err != nil {
-		log.Fatalf("%s: %s", "Error reading person", err)
-		return
-	}
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+	res, err = sendRequest(ctx, publisher, "cnp.customer", "customer.search", body, headers, options.Collect, options.Verbose)
 	return
 }
 
 func CustomerSearchFlagSet(options *CustomerSearchOptions) *flag.FlagSet {
 	fs := flag.NewFlagSet(CustomerSearchCmd, flag.ExitOnError)
 	fs.StringVar(&options.Id, "id", "", "Entity identifier")
-	fs.StringVar(&options.Username, "u", "", "Username")
-	fs.StringVar(&options.Authorities, "a", "", "Authorities")
+	authFlags(fs, &options.Username, &options.Authorities)
+	fs.DurationVar(&options.Timeout, "timeout", client.DefaultTimeout, "Reply timeout")
+	collectFlags(fs, &options.Collect)
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
 	return fs
 }
@@ -0,0 +1,174 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+const ReplayCmd = "replay"
+
+type ReplayModule struct {
+	Publisher client.Publisher
+}
+
+type replayOptions struct {
+	file    string
+	timeout time.Duration
+	delay   time.Duration
+	noDiff  bool
+	verbose bool
+	help    bool
+}
+
+func (m ReplayModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     ReplayCmd,
+		Category: CategoryTesting,
+		Short:    "Send the messages recorded with -record again and compare the replies",
+		Examples: []string{"hodei-cli replay -f session.jsonl -delay 500ms"},
+	}
+}
+
+func (m ReplayModule) Execute(ctx context.Context, args []string) error {
+	options := replayOptions{}
+	flagset := replayCreateFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.help {
+		printHelp(m.Info(), flagset)
+		return nil
+	} else if options.file == "" {
+		return fmt.Errorf("required record file")
+	}
+	return replay(ctx, m.Publisher, &options)
+}
+
+func replayCreateFlagSet(options *replayOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(ReplayCmd, flag.ExitOnError)
+	fs.StringVar(&options.file, "f", "", "Record file created with -record")
+	fs.DurationVar(&options.timeout, "timeout", client.DefaultTimeout, "Reply timeout")
+	fs.DurationVar(&options.delay, "delay", 0, "Pause between messages")
+	fs.BoolVar(&options.noDiff, "nodiff", false, "Do not compare replies with the recorded ones")
+	fs.BoolVar(&options.verbose, "v", false, "Verbose")
+	fs.BoolVar(&options.help, "help", false, "Help")
+	brokerFlags(fs)
+	return fs
+}
+
+// replay sends every recorded message again and returns an error when a
+// message fails or a reply differs from the recorded one.
+func replay(ctx context.Context, publisher client.Publisher, options *replayOptions) error {
+	records, err := client.ReadRecords(options.file)
+	if err != nil {
+		return fmt.Errorf("reading record file: %w", err)
+	}
+
+	failed, different := 0, 0
+	for i, record := range records {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if i > 0 && options.delay > 0 {
+			select {
+			case <-time.After(options.delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		fmt.Printf("[%d/%d] %s %s %s\n", i+1, len(records), record.Type, record.Exchange, record.RoutingKey)
+		if record.Type != client.RecordRequest {
+			if err := publisher.SendMessageWithHeaders(ctx, record.Exchange, record.RoutingKey, record.Body, record.HeadersTable(), options.verbose); err != nil {
+				fmt.Printf("  Error: %s\n", err)
+				failed++
+			}
+			continue
+		}
+		requestCtx, cancel := context.WithTimeout(ctx, options.timeout)
+		var res string
+		if record.Collect != nil {
+			var replies []string
+			replies, err = publisher.SendAndCollect(requestCtx, record.Exchange, record.RoutingKey, record.Body, record.HeadersTable(), *record.Collect, options.verbose)
+			res = strings.Join(replies, "\n")
+		} else {
+			res, err = publisher.SendAndReceive(requestCtx, record.Exchange, record.RoutingKey, record.Body, record.HeadersTable(), options.verbose)
+		}
+		cancel()
+		if err != nil {
+			fmt.Printf("  Error: %s\n", err)
+			failed++
+		} else if !options.noDiff && record.Error == "" {
+			if diff := diffLines(normalizeReply(record.Reply), normalizeReply(res)); len(diff) > 0 {
+				fmt.Printf("  Reply differs from %s\n", record.CorrelationId)
+				for _, line := range diff {
+					fmt.Println("  " + line)
+				}
+				different++
+			}
+		}
+	}
+	fmt.Printf("Replayed %d messages: %d failed, %d with different replies\n", len(records), failed, different)
+	if failed > 0 || different > 0 {
+		return fmt.Errorf("%d messages failed, %d with different replies", failed, different)
+	}
+	return nil
+}
+
+// normalizeReply pretty prints JSON replies so that formatting changes are
+// not reported as differences.
+func normalizeReply(reply string) []string {
+	var buffer bytes.Buffer
+	if json.Indent(&buffer, []byte(reply), "", "  ") == nil {
+		reply = buffer.String()
+	}
+	return strings.Split(strings.TrimSpace(reply), "\n")
+}
+
+// diffLines returns the lines removed from a (prefixed with -) and added in b
+// (prefixed with +), or nothing when both are equal.
+func diffLines(a []string, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var diff []string
+	changed := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff = append(diff, "  "+a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			diff = append(diff, "+ "+b[j])
+			changed = true
+			j++
+		default:
+			diff = append(diff, "- "+a[i])
+			changed = true
+			i++
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return diff
+}
@@ -0,0 +1,37 @@
+package modules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestReplay(t *testing.T) {
+	file, remove := tempFile(t, "session.jsonl", "")
+	defer remove()
+	recorder, err := client.OpenRecorder(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Write(client.Record{Type: client.RecordPublish, Exchange: "cnp.referential", RoutingKey: "country.pull"})
+	recorder.Write(client.Record{Type: client.RecordRequest, Exchange: "cnp.sepa", RoutingKey: "iban.validation", Body: "{}", Reply: `{"valid": true}`})
+	recorder.Write(client.Record{Type: client.RecordRequest, Exchange: "cnp.customer", RoutingKey: "customer.search", Body: "{}",
+		Reply: "{\"page\":1}\n{\"page\":2}", Collect: &client.CollectOptions{Count: 2}})
+	recorder.Close()
+
+	broker := client.NewFakeBroker()
+	broker.Reply("cnp.sepa", "iban.validation", `{"valid":true}`)
+	broker.Reply("cnp.customer", "customer.search", `{"page":1}`, `{"page":2}`)
+	if err := replay(context.Background(), broker, &replayOptions{file: file, timeout: client.DefaultTimeout}); err != nil {
+		t.Errorf("replay with equivalent replies reported differences: %s", err)
+	}
+	if len(broker.Messages) != 3 {
+		t.Errorf("replayed %d messages, want 3", len(broker.Messages))
+	}
+
+	broker.Reply("cnp.sepa", "iban.validation", `{"valid":false}`)
+	if err := replay(context.Background(), broker, &replayOptions{file: file, timeout: client.DefaultTimeout}); err == nil {
+		t.Error("replay with a different reply reported no differences")
+	}
+}
@@ -0,0 +1,28 @@
+package modules
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/streadway/amqp"
+)
+
+// collectFlags registers the options of request/reply commands for services
+// that answer with several messages.
+func collectFlags(fs *flag.FlagSet, collect *client.CollectOptions) {
+	fs.IntVar(&collect.Count, "replies", 0, "Number of replies to collect")
+	fs.StringVar(&collect.EndHeader, "end-header", "", "Header marking the last reply")
+	fs.DurationVar(&collect.Quiet, "quiet", 0, "Stop collecting replies after this time without messages")
+}
+
+// sendRequest returns the reply to a request or, when collecting several
+// replies, all of them one per line in arrival order.
+func sendRequest(ctx context.Context, publisher client.Publisher, exchange string, routingKey string, body string, headers amqp.Table, collect client.CollectOptions, verbose bool) (string, error) {
+	if !collect.Enabled() {
+		return publisher.SendAndReceive(ctx, exchange, routingKey, body, headers, verbose)
+	}
+	replies, err := publisher.SendAndCollect(ctx, exchange, routingKey, body, headers, collect, verbose)
+	return strings.Join(replies, "\n"), err
+}
@@ -0,0 +1,57 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestRequestCommands(t *testing.T) {
+	tests := []struct {
+		name       string
+		send       func(client.Publisher) (string, error)
+		exchange   string
+		routingKey string
+		body       string
+	}{
+		{"read-customer", func(p client.Publisher) (string, error) {
+			return CustomerSearch(context.Background(), p, &CustomerSearchOptions{Id: "42", Username: "u", Authorities: "a", Timeout: client.DefaultTimeout})
+		}, "cnp.customer", "customer.search", `{"1":{"type":"person","reference":"42"}}`},
+		{"check-iban", func(p client.Publisher) (string, error) {
+			return CheckIban(context.Background(), p, &CheckIbanOptions{CountryCode: "ESP", Iban: "ES0000", Timeout: client.DefaultTimeout})
+		}, "cnp.sepa", "iban.validation", `{"countryCode": "ESP","iban": "ES0000"}`},
+		{"signature-request", func(p client.Publisher) (string, error) {
+			return SignatureRequest(context.Background(), p, &SignatureRequestOptions{DocumentId: "d1", Username: "u", Authorities: "a", Timeout: client.DefaultTimeout})
+		}, "cnp.esignature", "signature.request", `{"documentId":"d1"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			broker := client.NewFakeBroker()
+			broker.Reply(test.exchange, test.routingKey, `{"ok":true}`)
+			res, err := test.send(broker)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if res != `{"ok":true}` {
+				t.Errorf("reply %s", res)
+			}
+			msg := broker.Last()
+			if !msg.Request || msg.Body != test.body {
+				t.Errorf("unexpected request %+v", msg)
+			}
+			if !registeredEndpoint(msg) {
+				t.Errorf("%s/%s missing from Endpoints", msg.Exchange, msg.RoutingKey)
+			}
+		})
+	}
+}
+
+func TestRequestWithoutReplyTimesOut(t *testing.T) {
+	broker := client.NewFakeBroker()
+	_, err := CheckIban(context.Background(), broker, &CheckIbanOptions{Iban: "ES0000", Timeout: client.DefaultTimeout})
+	if !errors.Is(err, client.ErrReplyTimeout) {
+		t.Errorf("expected timeout, got %v", err)
+	}
+}
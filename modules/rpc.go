@@ -0,0 +1,74 @@
+package modules
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/streadway/amqp"
+)
+
+const RpcCmd = "rpc"
+
+type RpcModule struct {
+	Publisher client.Publisher
+}
+
+type rpcOptions struct {
+	exchange   string
+	routingKey string
+	headers    headerFlags
+	body       string
+	file       string
+	timeout    time.Duration
+	collect    client.CollectOptions
+	verbose    bool
+	help       bool
+}
+
+func (m RpcModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     RpcCmd,
+		Category: CategoryMessaging,
+		Short:    "Send any request and print the reply of the service",
+		Examples: []string{"cat request.json | hodei-cli rpc -exchange cnp.sepa -key iban.validation -H App-Source=hodei-cli -file -"},
+	}
+}
+
+func (m RpcModule) Execute(ctx context.Context, args []string) error {
+	options := rpcOptions{headers: headerFlags{}}
+	flagset := rpcCreateFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return printReply(rpc(ctx, m.Publisher, &options))
+}
+
+func rpcCreateFlagSet(options *rpcOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(RpcCmd, flag.ExitOnError)
+	messageFlags(fs, &options.exchange, &options.routingKey, options.headers, &options.body, &options.file)
+	fs.DurationVar(&options.timeout, "timeout", client.DefaultTimeout, "Reply timeout")
+	collectFlags(fs, &options.collect)
+	fs.BoolVar(&options.verbose, "v", false, "Verbose")
+	fs.BoolVar(&options.help, "help", false, "Help")
+	brokerFlags(fs)
+	return fs
+}
+
+func rpc(ctx context.Context, publisher client.Publisher, options *rpcOptions) (string, error) {
+	if options.exchange == "" && options.routingKey == "" {
+		return "", fmt.Errorf("required exchange or routing key")
+	}
+	body, err := readBody(options.body, options.file)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, options.timeout)
+	defer cancel()
+	return sendRequest(ctx, publisher, options.exchange, options.routingKey, body, amqp.Table(options.headers), options.collect, options.verbose)
+}
@@ -0,0 +1,31 @@
+package modules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestRpc(t *testing.T) {
+	broker := client.NewFakeBroker()
+	broker.Reply("cnp.sepa", "iban.validation", "valid")
+	res, err := rpc(context.Background(), broker, &rpcOptions{exchange: "cnp.sepa", routingKey: "iban.validation", headers: headerFlags{}, timeout: client.DefaultTimeout})
+	if err != nil || res != "valid" {
+		t.Errorf("unexpected reply %s, %v", res, err)
+	}
+	if _, err := rpc(context.Background(), broker, &rpcOptions{headers: headerFlags{}}); err == nil {
+		t.Error("expected error without destination")
+	}
+}
+
+func TestRpcCollectsReplies(t *testing.T) {
+	broker := client.NewFakeBroker()
+	broker.Reply("cnp.customer", "customer.export", "page 1", "page 2", "page 3")
+	options := rpcOptions{exchange: "cnp.customer", routingKey: "customer.export", headers: headerFlags{}, timeout: client.DefaultTimeout}
+	options.collect.Count = 2
+	res, err := rpc(context.Background(), broker, &options)
+	if err != nil || res != "page 1\npage 2" {
+		t.Errorf("unexpected replies %q, %v", res, err)
+	}
+}
@@ -1,10 +1,11 @@
 package modules
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
 	"github.com/labcabrera/hodei-cli/client"
 	"github.com/streadway/amqp"
@@ -14,38 +15,67 @@ type SignatureRequestOptions struct {
 	DocumentId  string
 	Username    string
 	Authorities string
+	Timeout     time.Duration
+	Collect     client.CollectOptions
 	Verbose     bool
 	Help        bool
 }
 
+type SignatureRequestModule struct {
+	Publisher client.Publisher
+}
+
+func (m SignatureRequestModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     SignatureRequestCmd,
+		Category: CategoryServices,
+		Short:    "Request the signature of a document",
+		Examples: []string{"hodei-cli signature-request -id 1234 -u demo -a demo"},
+	}
+}
+
+func (m SignatureRequestModule) Execute(ctx context.Context, args []string) error {
+	options := SignatureRequestOptions{}
+	flagset := SignatureRequestFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.Help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	return printReply(SignatureRequest(ctx, m.Publisher, &options))
+}
+
 const SignatureRequestCmd = "signature-request"
 
-func SignatureRequest(options *SignatureRequestOptions) (res string, err error) {
+func SignatureRequest(ctx context.Context, publisher client.Publisher, options *SignatureRequestOptions) (res string, err error) {
 	if options.Verbose {
 		log.Printf("Sending signature request")
 	}
 	if options.DocumentId == "" {
-		fmt.Println("Required document identifier")
-		os.Exit(1)
+		return "", fmt.Errorf("required document identifier")
 	} else if options.Username == "" || options.Authorities == "" {
-		fmt.Println("Required authorization information")
-		os.Exit(1)
+		return "", fmt.Errorf("required authorization information")
 	}
 	headers := amqp.Table{
 		"App-Username":    options.Username,
 		"App-Authorities": options.Authorities,
 	}
 	body := `{"documentId":"` + options.DocumentId + `"}`
-	res, err = client.SendAndReceive("cnp.esignature", "signature.request", body, headers, options.Verbose)
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+	res, err = sendRequest(ctx, publisher, "cnp.esignature", "signature.request", body, headers, options.Collect, options.Verbose)
 	return
 }
 
 func SignatureRequestFlagSet(options *SignatureRequestOptions) *flag.FlagSet {
 	fs := flag.NewFlagSet(PullAgreementsCmd, flag.ExitOnError)
 	fs.StringVar(&options.DocumentId, "id", "", "Document identifier")
-	fs.StringVar(&options.Username, "u", "", "Username")
-	fs.StringVar(&options.Authorities, "a", "", "Authorities")
+	authFlags(fs, &options.Username, &options.Authorities)
+	fs.DurationVar(&options.Timeout, "timeout", client.DefaultTimeout, "Reply timeout")
+	collectFlags(fs, &options.Collect)
 	fs.BoolVar(&options.Verbose, "v", false, "Verbose")
+	brokerFlags(fs)
 	fs.BoolVar(&options.Help, "help", false, "Help")
 	return fs
 }
@@ -0,0 +1,136 @@
+package modules
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/streadway/amqp"
+)
+
+const TailCmd = "tail"
+const tailTimeFormat = "2006-01-02 15:04:05.000"
+
+type TailModule struct {
+}
+
+type tailOptions struct {
+	exchange   string
+	routingKey string
+	headers    headerFlags
+	grep       string
+	verbose    bool
+	help       bool
+}
+
+func (m TailModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     TailCmd,
+		Category: CategoryMessaging,
+		Short:    "Show the messages published to an exchange as they arrive",
+		Examples: []string{"hodei-cli tail -exchange cnp.referential -key '*.pull' -H App-Username=demo -grep 20725"},
+	}
+}
+
+func (m TailModule) Execute(ctx context.Context, args []string) error {
+	options := tailOptions{headers: headerFlags{}}
+	flagset := tailCreateFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.help {
+		printHelp(m.Info(), flagset)
+		return nil
+	} else if options.exchange == "" {
+		return fmt.Errorf("required exchange parameter")
+	}
+	return tail(ctx, &options)
+}
+
+func tailCreateFlagSet(options *tailOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(TailCmd, flag.ExitOnError)
+	fs.StringVar(&options.exchange, "exchange", "", "Exchange to listen to (cnp.referential, cnp.customer...)")
+	fs.StringVar(&options.routingKey, "key", "#", "Routing key pattern")
+	fs.Var(options.headers, "H", "Only show messages with header key=value (repeatable)")
+	fs.StringVar(&options.grep, "grep", "", "Only show messages whose body matches the regular expression")
+	fs.BoolVar(&options.verbose, "v", false, "Verbose")
+	fs.BoolVar(&options.help, "help", false, "Help")
+	brokerFlags(fs)
+	return fs
+}
+
+func tail(ctx context.Context, options *tailOptions) error {
+	var bodyFilter *regexp.Regexp
+	if options.grep != "" {
+		var err error
+		if bodyFilter, err = regexp.Compile(options.grep); err != nil {
+			return fmt.Errorf("invalid body filter: %w", err)
+		}
+	}
+
+	subscription, err := client.Subscribe(options.exchange, options.routingKey)
+	if err != nil {
+		return fmt.Errorf("subscribing: %w", err)
+	}
+	defer subscription.Close()
+
+	if options.verbose {
+		log.Printf("Listening on %s with routing key %s (queue %s)", options.exchange, options.routingKey, subscription.Queue)
+	}
+
+	for {
+		select {
+		case d, ok := <-subscription.Messages:
+			if !ok {
+				return fmt.Errorf("subscription closed by the broker")
+			}
+			if tailMatches(&d, options.headers, bodyFilter) {
+				printDelivery(&d)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func tailMatches(d *amqp.Delivery, headers headerFlags, bodyFilter *regexp.Regexp) bool {
+	for key, value := range headers {
+		if fmt.Sprint(d.Headers[key]) != value {
+			return false
+		}
+	}
+	return bodyFilter == nil || bodyFilter.Match(d.Body)
+}
+
+func printDelivery(d *amqp.Delivery) {
+	timestamp := d.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	fmt.Printf("--- %s %s %s\n", timestamp.Format(tailTimeFormat), d.Exchange, d.RoutingKey)
+	printProperty("Content-Type", d.ContentType)
+	printProperty("Correlation-Id", d.CorrelationId)
+	printProperty("Reply-To", d.ReplyTo)
+	printProperty("Message-Id", d.MessageId)
+	printProperty("App-Id", d.AppId)
+	keys := make([]string, 0, len(d.Headers))
+	for key := range d.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		printProperty(key, fmt.Sprint(d.Headers[key]))
+	}
+	fmt.Println(strings.TrimSpace(string(d.Body)))
+}
+
+func printProperty(name string, value string) {
+	if value != "" {
+		fmt.Printf("%s: %s\n", name, value)
+	}
+}
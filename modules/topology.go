@@ -0,0 +1,220 @@
+package modules
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"gopkg.in/yaml.v2"
+)
+
+const TopologyCmd = "topology"
+
+const (
+	topologyCheckCmd  = "check"
+	topologyApplyCmd  = "apply"
+	topologyExportCmd = "export"
+)
+
+const topologyTemplate = "%-18s|%-20s|%-18s|%s\n"
+
+type TopologyModule struct {
+}
+
+type topologyOptions struct {
+	file    string
+	verbose bool
+	help    bool
+}
+
+func (m TopologyModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     TopologyCmd,
+		Category: CategoryBroker,
+		Short:    "Check, apply or export the exchanges and queues used by the cli",
+		Long: `Check, apply or export the RabbitMQ topology used by the cli commands.
+
+Commands:
+  ` + topologyCheckCmd + `   Check the exchanges and bindings used by the cli commands
+  ` + topologyApplyCmd + `   Declare the exchanges, queues and bindings of a topology file
+  ` + topologyExportCmd + `  Write the topology used by the cli commands`,
+		Examples: []string{
+			"hodei-cli topology check",
+			"hodei-cli topology export -o topology.yaml",
+			"hodei-cli topology apply -f topology.yaml",
+		},
+	}
+}
+
+func (m TopologyModule) Execute(ctx context.Context, args []string) error {
+	if len(args) == 0 || isHelp(args[0]) {
+		fmt.Println(m.Info().Long)
+		fmt.Printf("\nRun 'hodei-cli %s COMMAND -help' for the options of a command.\n", TopologyCmd)
+		if len(args) == 0 {
+			return fmt.Errorf("missing %s command", TopologyCmd)
+		}
+		return nil
+	}
+	cmd := args[0]
+	options := topologyOptions{}
+	flagset := topologyCreateFlagSet(cmd, &options)
+	flagset.Parse(args[1:])
+
+	if options.help {
+		printHelp(ModuleInfo{Name: TopologyCmd + " " + cmd, Short: m.Info().Short}, flagset)
+		return nil
+	}
+	switch cmd {
+	case topologyCheckCmd:
+		return topologyCheck(&options)
+	case topologyApplyCmd:
+		return topologyApply(&options)
+	case topologyExportCmd:
+		return topologyExport(&options)
+	}
+	return fmt.Errorf("unknown %s command '%s'", TopologyCmd, cmd)
+}
+
+func topologyCreateFlagSet(cmd string, options *topologyOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(TopologyCmd+" "+cmd, flag.ExitOnError)
+	switch cmd {
+	case topologyApplyCmd:
+		fs.StringVar(&options.file, "f", "", "Topology YAML file")
+	case topologyExportCmd:
+		fs.StringVar(&options.file, "o", "", "Output file (optional. Default stdout)")
+	}
+	fs.BoolVar(&options.verbose, "v", false, "Verbose")
+	fs.BoolVar(&options.help, "help", false, "Help")
+	brokerFlags(fs)
+	return fs
+}
+
+// topologyCheck verifies that every exchange in Endpoints exists and has at
+// least one binding routing each of its keys.
+func topologyCheck(options *topologyOptions) error {
+	management, err := client.NewManagement(*client.DefaultConfig())
+	if err != nil {
+		return fmt.Errorf("configuring management API: %w", err)
+	}
+
+	byExchange := make(map[string][]Endpoint)
+	for _, endpoint := range Endpoints {
+		byExchange[endpoint.Exchange] = append(byExchange[endpoint.Exchange], endpoint)
+	}
+
+	ok := true
+	fmt.Printf(topologyTemplate, "Exchange", "Routing key", "Command", "Status")
+	for _, exchange := range endpointExchanges() {
+		// Profiles may rename the exchanges of the environment
+		name := client.DefaultConfig().ExchangeName(exchange)
+		exists, err := client.ExchangeExists(name)
+		if err != nil {
+			return fmt.Errorf("checking exchange: %w", err)
+		}
+		var info *client.ExchangeInfo
+		var bindings []client.Binding
+		if exists {
+			if info, err = management.Exchange(name); err == nil {
+				bindings, err = management.Bindings(name)
+			}
+			if err != nil {
+				return fmt.Errorf("reading bindings: %w", err)
+			}
+			if options.verbose {
+				log.Printf("Exchange %s (%s) has %d bindings", name, info.Type, len(bindings))
+			}
+		}
+		for _, endpoint := range byExchange[exchange] {
+			status := "OK"
+			if !exists {
+				status = "MISSING EXCHANGE"
+				ok = false
+			} else if queues := routedQueues(info.Type, bindings, endpoint.RoutingKey); len(queues) == 0 {
+				status = "NO BOUND QUEUES"
+				ok = false
+			} else if options.verbose {
+				status = "OK (" + strings.Join(queues, ", ") + ")"
+			}
+			fmt.Printf(topologyTemplate, name, endpoint.RoutingKey, endpoint.Command, status)
+		}
+	}
+	if !ok {
+		return fmt.Errorf("topology check failed")
+	}
+	return nil
+}
+
+func routedQueues(exchangeType string, bindings []client.Binding, routingKey string) []string {
+	var queues []string
+	for _, binding := range bindings {
+		if binding.Routes(exchangeType, routingKey) {
+			queues = append(queues, binding.Destination)
+		}
+	}
+	return queues
+}
+
+func topologyApply(options *topologyOptions) error {
+	if options.file == "" {
+		return fmt.Errorf("required topology file")
+	}
+	content, err := ioutil.ReadFile(options.file)
+	if err != nil {
+		return fmt.Errorf("reading topology: %w", err)
+	}
+	topology := client.Topology{}
+	if err = yaml.UnmarshalStrict(content, &topology); err != nil {
+		return fmt.Errorf("parsing topology: %w", err)
+	}
+	if err = client.ApplyTopology(topology, options.verbose); err != nil {
+		return fmt.Errorf("applying topology: %w", err)
+	}
+	fmt.Printf("Declared %d exchanges, %d queues and %d bindings\n", len(topology.Exchanges), len(topology.Queues), len(topology.Bindings))
+	return nil
+}
+
+func topologyExport(options *topologyOptions) error {
+	content, err := yaml.Marshal(endpointsTopology())
+	if err != nil {
+		return fmt.Errorf("writing topology: %w", err)
+	}
+	if options.file == "" {
+		_, err = os.Stdout.Write(content)
+	} else {
+		err = ioutil.WriteFile(options.file, content, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("writing topology: %w", err)
+	}
+	return nil
+}
+
+// endpointsTopology builds a development topology for Endpoints: a durable
+// topic exchange per exchange with a dead-letter exchange, and for every
+// routing key a queue with its dead-letter queue.
+func endpointsTopology() client.Topology {
+	topology := client.Topology{}
+	exchanges := make(map[string]bool)
+	for _, endpoint := range Endpoints {
+		dlx := endpoint.Exchange + ".dlx"
+		if !exchanges[endpoint.Exchange] {
+			exchanges[endpoint.Exchange] = true
+			topology.Exchanges = append(topology.Exchanges,
+				client.ExchangeSpec{Name: endpoint.Exchange, Type: "topic", Durable: true},
+				client.ExchangeSpec{Name: dlx, Type: "topic", Durable: true})
+		}
+		queue := endpoint.Exchange + "." + endpoint.RoutingKey
+		topology.Queues = append(topology.Queues,
+			client.QueueSpec{Name: queue, Durable: true, DeadLetterExchange: dlx},
+			client.QueueSpec{Name: queue + ".dlq", Durable: true})
+		topology.Bindings = append(topology.Bindings,
+			client.BindingSpec{Exchange: endpoint.Exchange, Queue: queue, RoutingKey: endpoint.RoutingKey},
+			client.BindingSpec{Exchange: dlx, Queue: queue + ".dlq", RoutingKey: endpoint.RoutingKey})
+	}
+	return topology
+}
@@ -0,0 +1,24 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/labcabrera/hodei-cli/client"
+)
+
+func TestRoutedQueues(t *testing.T) {
+	bindings := []client.Binding{
+		{Destination: "customers", RoutingKey: "customer.*"},
+		{Destination: "all", RoutingKey: "#"},
+		{Destination: "policies", RoutingKey: "policy.pull"},
+	}
+	if queues := routedQueues("topic", bindings, "customer.pull"); len(queues) != 2 {
+		t.Errorf("topic routed to %v", queues)
+	}
+	if queues := routedQueues("direct", bindings, "customer.pull"); len(queues) != 0 {
+		t.Errorf("direct routed to %v", queues)
+	}
+	if queues := routedQueues("fanout", bindings, "anything"); len(queues) != 3 {
+		t.Errorf("fanout routed to %v", queues)
+	}
+}
@@ -0,0 +1,119 @@
+package modules
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/streadway/amqp"
+)
+
+const VerifyCmd = "verify"
+
+type VerifyModule struct {
+	Browser client.Browser
+}
+
+type verifyOptions struct {
+	headers headerFlags
+	body    string
+	file    string
+	queue   string
+	limit   int
+	maxAge  time.Duration
+	verbose bool
+	help    bool
+}
+
+func (m VerifyModule) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:     VerifyCmd,
+		Category: CategoryMessaging,
+		Short:    "Verify the HMAC signature of messages",
+		Long: `Check the App-Signature header of a message given as headers and body, or of
+the messages of a queue, which are left in it. The key is read from the
+signingKey of the profile or from APP_AMQP_SIGNING_KEY.`,
+		Examples: []string{
+			"hodei-cli verify -H App-Username=demo -H 'App-Signature=keyId=ops;...' -body '{\"id\":\"1\"}'",
+			"hodei-cli verify -queue cnp.customers.dlq -limit 10",
+		},
+	}
+}
+
+func (m VerifyModule) Execute(ctx context.Context, args []string) error {
+	options := verifyOptions{headers: headerFlags{}}
+	flagset := verifyCreateFlagSet(&options)
+	flagset.Parse(args)
+
+	if options.help {
+		printHelp(m.Info(), flagset)
+		return nil
+	}
+	if client.DefaultConfig().Signing.Key == "" {
+		return fmt.Errorf("signing key is not defined, set signingKey in the profile or APP_AMQP_SIGNING_KEY")
+	}
+	if options.queue != "" {
+		return verifyQueue(m.Browser, &options)
+	}
+	return verifyMessage(&options)
+}
+
+func verifyCreateFlagSet(options *verifyOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(VerifyCmd, flag.ExitOnError)
+	fs.Var(options.headers, "H", "Message header key=value (repeatable)")
+	fs.StringVar(&options.body, "body", "", "Message body")
+	fs.StringVar(&options.file, "file", "", "Read the message body from a file, - for stdin")
+	fs.StringVar(&options.queue, "queue", "", "Verify the messages of a queue instead, without consuming them")
+	fs.IntVar(&options.limit, "limit", 100, "Max number of queue messages (0 for all)")
+	fs.DurationVar(&options.maxAge, "max-age", 0, "Reject signatures older than this (0 to accept any age)")
+	fs.BoolVar(&options.verbose, "v", false, "Verbose")
+	fs.BoolVar(&options.help, "help", false, "Help")
+	brokerFlags(fs)
+	return fs
+}
+
+// verifyMessage checks a message captured for example with tail, given as
+// headers and body.
+func verifyMessage(options *verifyOptions) error {
+	body, err := readBody(options.body, options.file)
+	if err != nil {
+		return err
+	}
+	return verifyPrint("", amqp.Table(options.headers), []byte(body), options.maxAge)
+}
+
+// verifyQueue checks the messages of a queue, which are left in it.
+// It returns the last invalid signature error, if any.
+func verifyQueue(browser client.Browser, options *verifyOptions) error {
+	queue, err := browser.Browse(options.queue, options.limit)
+	if err != nil {
+		return err
+	}
+	defer queue.Close()
+	messages := queue.Messages()
+	if len(messages) == 0 {
+		fmt.Printf("Queue %s is empty\n", options.queue)
+		return nil
+	}
+	var invalid error
+	for i, d := range messages {
+		prefix := fmt.Sprintf("[%d] %s %s: ", i+1, d.Exchange, d.RoutingKey)
+		if err := verifyPrint(prefix, d.Headers, d.Body, options.maxAge); err != nil {
+			invalid = err
+		}
+	}
+	return invalid
+}
+
+func verifyPrint(prefix string, headers amqp.Table, body []byte, maxAge time.Duration) error {
+	signature, err := client.DefaultConfig().Signing.Verify(headers, body, maxAge, time.Now())
+	if err != nil {
+		fmt.Printf("%sINVALID %s\n", prefix, err)
+		return err
+	}
+	fmt.Printf("%sOK signed with key '%s' at %s (headers %v)\n", prefix, signature.KeyId,
+		signature.Timestamp.Format(time.RFC3339), signature.Headers)
+	return nil
+}
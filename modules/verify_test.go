@@ -0,0 +1,59 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/labcabrera/hodei-cli/client"
+	"github.com/streadway/amqp"
+)
+
+func TestSignature(t *testing.T) {
+	signing := client.SigningOptions{Key: "secret", KeyId: "ops", Headers: client.DefaultSignedHeaders}
+	signed := time.Now()
+	body := []byte(`{"idCard":"70111222A"}`)
+	headers := signing.Sign(amqp.Table{"App-Username": "demo", "App-Authorities": "admin"}, body, signed)
+
+	// Headers captured with tail are plain strings
+	captured := amqp.Table{}
+	for key, value := range headers {
+		captured[key] = fmt.Sprint(value)
+	}
+	if signature, err := signing.Verify(captured, body, time.Minute, signed); err != nil || signature.KeyId != "ops" {
+		t.Errorf("unexpected verification %v, %v", signature, err)
+	}
+
+	tampered := amqp.Table{}
+	for key, value := range captured {
+		tampered[key] = value
+	}
+	tampered["App-Authorities"] = "root"
+	// A signature without headers leaves the authorities unauthenticated
+	unsignedHeaders := client.SigningOptions{Key: "secret", KeyId: "ops"}.Sign(tampered, body, signed)
+	// Missing headers are not signed as empty ones
+	withoutAuthorities := signing.Sign(amqp.Table{"App-Username": "demo"}, body, signed)
+	withoutAuthorities["App-Authorities"] = ""
+	tests := []struct {
+		name    string
+		signing client.SigningOptions
+		headers amqp.Table
+		body    []byte
+		now     time.Time
+	}{
+		{"tampered header", signing, tampered, body, signed},
+		{"tampered body", signing, captured, []byte(`{}`), signed},
+		{"wrong key", client.SigningOptions{Key: "other"}, captured, body, signed},
+		{"unknown key id", client.SigningOptions{Key: "secret", KeyId: "dev"}, captured, body, signed},
+		{"expired", signing, captured, body, signed.Add(time.Hour)},
+		{"unsigned", signing, amqp.Table{}, body, signed},
+		{"headers not signed", signing, unsignedHeaders, body, signed},
+		{"empty header added", signing, withoutAuthorities, body, signed},
+	}
+	for _, test := range tests {
+		if _, err := test.signing.Verify(test.headers, test.body, time.Minute, test.now); !errors.Is(err, client.ErrInvalidSignature) {
+			t.Errorf("%s: expected invalid signature, got %v", test.name, err)
+		}
+	}
+}